@@ -0,0 +1,164 @@
+package apigw
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CanaryPercent the percentage of the stage traffic sent to the new deployment, a canary is deployed when set
+	CanaryPercent = "CANARY_PERCENT"
+	// HealthCheckURL the endpoint polled through the stage before the canary is promoted
+	HealthCheckURL = "HEALTH_CHECK_URL"
+
+	DefaultHealthCheckRequests = 10
+	DefaultHealthCheckInterval = 2 * time.Second
+	defaultHealthCheckTimeout  = 10 * time.Second
+)
+
+// Canary the canary deployment of the stage, the stage keeps serving its deployment to the rest of the traffic until the
+// canary is promoted or rolled back
+type Canary struct {
+	// Percent the percentage of the traffic, between 0 and 100, no canary is deployed when 0
+	Percent       float64     `yaml:"percent"`
+	UseStageCache bool        `yaml:"useStageCache"`
+	HealthCheck   HealthCheck `yaml:"healthCheck"`
+}
+
+// HealthCheck the endpoint polled before the promotion, all the requests must return a 2xx response
+type HealthCheck struct {
+	URL      string        `yaml:"url"`
+	Requests int           `yaml:"requests"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Enabled tells whether a canary is deployed
+func (c Canary) Enabled() bool {
+	return c.Percent > 0
+}
+
+// Validate returns the problems of the canary settings
+func (c Canary) Validate() []string {
+	var problems []string
+	if c.Percent < 0 || c.Percent > 100 {
+		problems = append(problems, fmt.Sprintf("the canary percent (%s) %v must be between 0 and 100", CanaryPercent, c.Percent))
+	}
+	if c.HealthCheck.URL != "" {
+		if u, err := url.Parse(c.HealthCheck.URL); err != nil || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("invalid health check url (%s) %q", HealthCheckURL, c.HealthCheck.URL))
+		}
+	}
+	if c.HealthCheck.Requests < 0 || c.HealthCheck.Interval < 0 || c.HealthCheck.Timeout < 0 {
+		problems = append(problems, "the health check requests, interval and timeout must not be negative")
+	}
+	return problems
+}
+
+// CreateCanaryDeployment deploys the recent upload as the canary of the stage, the variables are overridden for the
+// canary only
+func (cl APIGatewayClient) CreateCanaryDeployment(stage string, apigwId string, variables map[string]string, canary Canary) (*apigateway.Deployment, error) {
+	log.WithFields(log.Fields{"stage": stage, "API GatewayId": apigwId, "percent": canary.Percent}).Info("Deploying canary")
+	settings := apigateway.DeploymentCanarySettings{
+		PercentTraffic: aws.Float64(canary.Percent),
+		UseStageCache:  aws.Bool(canary.UseStageCache),
+	}
+	if len(variables) > 0 {
+		settings.StageVariableOverrides = aws.StringMap(variables)
+	}
+	return cl.apigw.CreateDeployment(&apigateway.CreateDeploymentInput{RestApiId: &apigwId, StageName: &stage, CanarySettings: &settings})
+}
+
+// PromoteCanary serves the canary deployment, and its stage variable overrides, to all the traffic and removes the canary
+func (cl APIGatewayClient) PromoteCanary(stage string, apigwId string) error {
+	current, err := cl.apigw.GetStage(&apigateway.GetStageInput{RestApiId: &apigwId, StageName: &stage})
+	if err != nil {
+		return err
+	}
+	patches, err := promotionPatches(current)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"stage": stage, "deployment": aws.StringValue(current.CanarySettings.DeploymentId)}).Info("Promoting canary")
+	_, err = cl.apigw.UpdateStage(&apigateway.UpdateStageInput{RestApiId: &apigwId, StageName: &stage, PatchOperations: patches})
+	return err
+}
+
+// RollbackCanary removes the canary, the stage deployment serves all the traffic again
+func (cl APIGatewayClient) RollbackCanary(stage string, apigwId string) error {
+	current, err := cl.apigw.GetStage(&apigateway.GetStageInput{RestApiId: &apigwId, StageName: &stage})
+	if err != nil {
+		return err
+	}
+	if current.CanarySettings == nil {
+		return fmt.Errorf("the stage %s has no canary to roll back", stage)
+	}
+	log.WithFields(log.Fields{"stage": stage, "deployment": aws.StringValue(current.CanarySettings.DeploymentId)}).Info("Rolling back canary")
+	_, err = cl.apigw.UpdateStage(&apigateway.UpdateStageInput{
+		RestApiId:       &apigwId,
+		StageName:       &stage,
+		PatchOperations: []*apigateway.PatchOperation{{Op: aws.String(apigateway.OpRemove), Path: aws.String("/canarySettings")}},
+	})
+	return err
+}
+
+// promotionPatches returns the operations moving the canary deployment and variables to the stage
+func promotionPatches(stage *apigateway.Stage) ([]*apigateway.PatchOperation, error) {
+	canary := stage.CanarySettings
+	if canary == nil || aws.StringValue(canary.DeploymentId) == "" {
+		return nil, fmt.Errorf("the stage %s has no canary to promote", aws.StringValue(stage.StageName))
+	}
+	replace := func(path string, value string) *apigateway.PatchOperation {
+		return &apigateway.PatchOperation{Op: aws.String(apigateway.OpReplace), Path: aws.String(path), Value: aws.String(value)}
+	}
+
+	patches := []*apigateway.PatchOperation{replace("/deploymentId", aws.StringValue(canary.DeploymentId))}
+	var names []string
+	for name := range canary.StageVariableOverrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		patches = append(patches, replace("/variables/"+name, aws.StringValue(canary.StageVariableOverrides[name])))
+	}
+	return append(patches, &apigateway.PatchOperation{Op: aws.String(apigateway.OpRemove), Path: aws.String("/canarySettings")}), nil
+}
+
+// CheckHealth polls the endpoint, every request must return a 2xx response. The canary only receives its share of the
+// requests, the more requests the more likely it is to be checked.
+func CheckHealth(check HealthCheck) error {
+	requests, interval, timeout := check.Requests, check.Interval, check.Timeout
+	if requests == 0 {
+		requests = DefaultHealthCheckRequests
+	}
+	if interval == 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	client := http.Client{Timeout: timeout}
+
+	for i := 1; i <= requests; i++ {
+		response, err := client.Get(check.URL)
+		if err != nil {
+			return fmt.Errorf("health check %d/%d of %s failed: %s", i, requests, check.URL, err)
+		}
+		response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			return fmt.Errorf("health check %d/%d of %s failed with http code %d", i, requests, check.URL, response.StatusCode)
+		}
+		log.WithFields(log.Fields{"url": check.URL, "status": response.StatusCode}).Infof("Health check %d/%d passed", i, requests)
+		if i < requests {
+			time.Sleep(interval)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package apigw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+func TestPromoteCanary_ShouldMoveTheCanaryDeploymentToTheStage(t *testing.T) {
+
+	t.Logf("Given a stage with a canary overriding the endpoint url variable")
+	{
+		stage := &apigateway.Stage{
+			StageName:    aws.String("prod"),
+			DeploymentId: aws.String("dep1"),
+			CanarySettings: &apigateway.CanarySettings{
+				DeploymentId:           aws.String("dep2"),
+				PercentTraffic:         aws.Float64(10),
+				StageVariableOverrides: aws.StringMap(map[string]string{"endpointUrl": "orders-v2.internal"}),
+			},
+		}
+
+		t.Logf("\tWhen promoting the canary, the stage should serve its deployment and variables")
+		{
+			patches, err := promotionPatches(stage)
+			if err != nil {
+				t.Fatalf("\t\tFailed to promote the canary %v %v", err, BallotX)
+			}
+			var operations []string
+			for _, patch := range patches {
+				operations = append(operations, *patch.Op+" "+*patch.Path+" "+aws.StringValue(patch.Value))
+			}
+			expected := []string{"replace /deploymentId dep2", "replace /variables/endpointUrl orders-v2.internal", "remove /canarySettings "}
+			if reflect.DeepEqual(operations, expected) {
+				t.Logf("\t\tThe canary should be promoted %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe canary should be promoted, got %v %v", operations, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given a stage without canary")
+	{
+		t.Logf("\tWhen promoting the canary, an error should be returned")
+		{
+			if _, err := promotionPatches(&apigateway.Stage{StageName: aws.String("prod")}); err != nil {
+				t.Logf("\t\tThe missing canary should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe missing canary should be reported %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestCheckHealth_ShouldFailOnTheFirstUnhealthyResponse(t *testing.T) {
+
+	t.Logf("Given a stage failing its third health check")
+	{
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 3 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		t.Logf("\tWhen checking the health, the check should stop at the failure")
+		{
+			err := CheckHealth(HealthCheck{URL: server.URL, Requests: 5, Interval: time.Millisecond})
+			if err != nil && calls == 3 {
+				t.Logf("\t\tThe unhealthy response should fail the check %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe unhealthy response should fail the check, got %v after %d calls %v", err, calls, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen checking the health again, the healthy responses should pass")
+		{
+			if err := CheckHealth(HealthCheck{URL: server.URL, Requests: 2, Interval: time.Millisecond}); err == nil {
+				t.Logf("\t\tThe healthy responses should pass the check %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe healthy responses should pass the check, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
@@ -1,15 +1,16 @@
 package apigw
 
 import (
-	"github.com/akhettar/apigw-pub/utils"
+	"encoding/json"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/apigateway"
+	swg "github.com/go-openapi/spec"
 	log "github.com/sirupsen/logrus"
-	"os"
 )
 
 const (
@@ -25,17 +26,20 @@ type APIGatewayClient struct {
 // Initial credentials loaded from SDK's default credential chain. Such as
 // the environment, shared credentials (~/.aws/credentials), or EC2 Instance
 // The assume role is used when run locally against the dev environment
-func NewAPIGatewayClient() APIGatewayClient {
+func NewAPIGatewayClient(region string, assumeRole string) APIGatewayClient {
+	if region == "" {
+		region = endpoints.EuWest1RegionID
+	}
 
 	// Session
 	ses := session.Must(session.NewSession(&aws.Config{
-		Region:      aws.String(utils.FetchEnvVar(Region, endpoints.EuWest1RegionID)),
+		Region:      aws.String(region),
 		Credentials: credentials.NewEnvCredentials(),
 	}))
 
-	if urn, ok := os.LookupEnv(AssumeRole); ok {
-		log.WithFields(log.Fields{}).Info("Running with assuming role: ", urn)
-		return createClientWithAssumeRole(ses, urn)
+	if assumeRole != "" {
+		log.WithFields(log.Fields{}).Info("Running with assuming role: ", assumeRole)
+		return createClientWithAssumeRole(ses, assumeRole)
 
 	}
 	// running with aws iam user which has permission to publish to api gateway
@@ -50,10 +54,38 @@ func (cl APIGatewayClient) ImportSwagger(swaggerDoc []byte, apigwId string) (*ap
 	return cl.apigw.PutRestApi(&put)
 }
 
-// CreateDeployment for the recent upload
-func (cl APIGatewayClient) CreateDeployment(stage string, apigwId string) (*apigateway.Deployment, error) {
+// ExportSwagger exports the swagger doc currently deployed to the given stage along with its integrations and authorizers.
+// An empty document is returned when the stage has not been deployed yet
+func (cl APIGatewayClient) ExportSwagger(stage string, apigwId string) (swg.Swagger, error) {
+	log.WithFields(log.Fields{"stage": stage, "API Gateway": apigwId}).Info("Exporting deployed swagger")
+	var doc swg.Swagger
+	export := apigateway.GetExportInput{
+		RestApiId:  &apigwId,
+		StageName:  &stage,
+		ExportType: aws.String("swagger"),
+		Accepts:    aws.String("application/json"),
+		Parameters: map[string]*string{"extensions": aws.String("integrations,authorizers")},
+	}
+	output, err := cl.apigw.GetExport(&export)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == apigateway.ErrCodeNotFoundException {
+			log.WithFields(log.Fields{"stage": stage}).Warn("Stage not found, comparing against an empty API")
+			return doc, nil
+		}
+		return doc, err
+	}
+	err = json.Unmarshal(output.Body, &doc)
+	return doc, err
+}
+
+// CreateDeployment for the recent upload, the variables are set on the stage
+func (cl APIGatewayClient) CreateDeployment(stage string, apigwId string, variables map[string]string) (*apigateway.Deployment, error) {
 	log.WithFields(log.Fields{"stage": stage, "API GatewayId": apigwId}).Info("Deploying API")
 	createDep := apigateway.CreateDeploymentInput{RestApiId: &apigwId, StageName: &stage}
+	if len(variables) > 0 {
+		log.WithFields(log.Fields{"stage": stage, "variables": variables}).Info("Setting stage variables")
+		createDep.Variables = aws.StringMap(variables)
+	}
 	return cl.apigw.CreateDeployment(&createDep)
 }
 
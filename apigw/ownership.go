@@ -0,0 +1,293 @@
+package apigw
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/swagger"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	swg "github.com/go-openapi/spec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// OwnerProperty the documentation property recording the owner of a published method
+	OwnerProperty          = "x-apigw-pub-owner"
+	DocumentationExtension = "x-amazon-apigateway-documentation"
+
+	OverwriteMode = apigateway.PutModeOverwrite
+	MergeMode     = apigateway.PutModeMerge
+
+	methodDocumentation = "METHOD"
+	pageSize            = 500
+)
+
+// OwnedRoute a method of the REST API published by a given owner
+type OwnedRoute struct {
+	Method string
+	Path   string
+	Owner  string
+	// the documentation part the ownership is recorded in
+	documentationPartID string
+}
+
+func (route OwnedRoute) String() string {
+	return fmt.Sprintf("%s %s", route.Method, route.Path)
+}
+
+// MergeSwagger imports the swagger doc into API Gateway in merge mode, the resources missing from the document are left
+// untouched
+func (cl APIGatewayClient) MergeSwagger(swaggerDoc []byte, apigwId string) (*apigateway.RestApi, error) {
+	log.WithFields(log.Fields{"API Gateway": apigwId}).Info("Merging swagger")
+	put := apigateway.PutRestApiInput{RestApiId: &apigwId, Body: swaggerDoc, Mode: aws.String(MergeMode)}
+	return cl.apigw.PutRestApi(&put)
+}
+
+// ImportOwnedSwagger - Function
+// Imports the swagger doc in merge mode on behalf of the given owner. The owner is recorded on every method of the
+// document, the methods it published before and which are no longer in the document are deleted. The methods of the
+// other owners are never touched: the import is refused when the document contains one of them.
+func (cl APIGatewayClient) ImportOwnedSwagger(swaggerDoc []byte, apigwId string, owner string) (*apigateway.RestApi, error) {
+	var doc swg.Swagger
+	if err := json.Unmarshal(swaggerDoc, &doc); err != nil {
+		return nil, err
+	}
+	owned, err := cl.OwnedRoutes(apigwId)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := documentRoutes(doc)
+	if conflicts := conflictingRoutes(routes, owned, owner); len(conflicts) > 0 {
+		return nil, fmt.Errorf("the document contains routes owned by other owners: %s", strings.Join(conflicts, ", "))
+	}
+
+	recorded, err := recordOwnership(swaggerDoc, routes, owner)
+	if err != nil {
+		return nil, err
+	}
+	restApi, err := cl.MergeSwagger(recorded, apigwId)
+	if err != nil {
+		return nil, err
+	}
+
+	if stale := StaleRoutes(doc, owned, owner); len(stale) > 0 {
+		if err := cl.deleteRoutes(apigwId, stale); err != nil {
+			return restApi, err
+		}
+	}
+	return restApi, nil
+}
+
+// OwnedRoutes returns the methods of the REST API along with their recorded owner
+func (cl APIGatewayClient) OwnedRoutes(apigwId string) ([]OwnedRoute, error) {
+	var routes []OwnedRoute
+	input := apigateway.GetDocumentationPartsInput{
+		RestApiId: &apigwId,
+		Type:      aws.String(methodDocumentation),
+		Limit:     aws.Int64(pageSize),
+	}
+	for {
+		output, err := cl.apigw.GetDocumentationParts(&input)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range output.Items {
+			if route, ok := ownedRoute(part); ok {
+				routes = append(routes, route)
+			}
+		}
+		if output.Position == nil || *output.Position == "" {
+			return routes, nil
+		}
+		input.Position = output.Position
+	}
+}
+
+// StaleRoutes returns the routes published by the owner which are no longer in the document
+func StaleRoutes(doc swg.Swagger, owned []OwnedRoute, owner string) []OwnedRoute {
+	current := map[string]bool{}
+	for _, route := range documentRoutes(doc) {
+		current[route.String()] = true
+	}
+	var stale []OwnedRoute
+	for _, route := range owned {
+		if route.Owner == owner && !current[route.String()] {
+			stale = append(stale, route)
+		}
+	}
+	return stale
+}
+
+func ownedRoute(part *apigateway.DocumentationPart) (OwnedRoute, bool) {
+	if part.Location == nil || part.Properties == nil {
+		return OwnedRoute{}, false
+	}
+	var properties map[string]interface{}
+	if err := json.Unmarshal([]byte(*part.Properties), &properties); err != nil {
+		return OwnedRoute{}, false
+	}
+	owner, ok := properties[OwnerProperty].(string)
+	if !ok {
+		return OwnedRoute{}, false
+	}
+	return OwnedRoute{
+		Method:              strings.ToUpper(aws.StringValue(part.Location.Method)),
+		Path:                aws.StringValue(part.Location.Path),
+		Owner:               owner,
+		documentationPartID: aws.StringValue(part.Id),
+	}, true
+}
+
+// conflictingRoutes returns the routes of the document owned by another owner
+func conflictingRoutes(routes []OwnedRoute, owned []OwnedRoute, owner string) []string {
+	owners := map[string]string{}
+	for _, route := range owned {
+		owners[route.String()] = route.Owner
+	}
+	var conflicts []string
+	for _, route := range routes {
+		if current, ok := owners[route.String()]; ok && current != owner {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", route, current))
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// recordOwnership adds a documentation part recording the owner of every method of the document
+func recordOwnership(swaggerDoc []byte, routes []OwnedRoute, owner string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(swaggerDoc, &doc); err != nil {
+		return nil, err
+	}
+
+	documentation, _ := doc[DocumentationExtension].(map[string]interface{})
+	if documentation == nil {
+		documentation = map[string]interface{}{}
+	}
+	parts, _ := documentation["documentationParts"].([]interface{})
+	for _, route := range routes {
+		parts = append(parts, map[string]interface{}{
+			"location":   map[string]interface{}{"type": methodDocumentation, "path": route.Path, "method": route.Method},
+			"properties": map[string]interface{}{OwnerProperty: owner},
+		})
+	}
+	documentation["documentationParts"] = parts
+	doc[DocumentationExtension] = documentation
+	return json.Marshal(doc)
+}
+
+// deleteRoutes deletes the methods along with their ownership record, the resources left without any method nor child
+// resource are deleted as well
+func (cl APIGatewayClient) deleteRoutes(apigwId string, routes []OwnedRoute) error {
+	resources := map[string]*apigateway.Resource{}
+	input := apigateway.GetResourcesInput{RestApiId: &apigwId, Limit: aws.Int64(pageSize), Embed: aws.StringSlice([]string{"methods"})}
+	err := cl.apigw.GetResourcesPages(&input,
+		func(output *apigateway.GetResourcesOutput, lastPage bool) bool {
+			for _, resource := range output.Items {
+				resources[aws.StringValue(resource.Path)] = resource
+			}
+			return true
+		})
+	if err != nil {
+		return err
+	}
+
+	// the methods left on the resources the routes are deleted from
+	methods := map[string]int{}
+	for _, route := range routes {
+		if resource, ok := resources[route.Path]; ok {
+			methods[route.Path] = len(resource.ResourceMethods)
+		}
+	}
+	for _, route := range routes {
+		log.WithFields(log.Fields{"route": route.String(), "owner": route.Owner}).Info("Deleting route no longer published")
+		if resource, ok := resources[route.Path]; ok {
+			_, err := cl.apigw.DeleteMethod(&apigateway.DeleteMethodInput{RestApiId: &apigwId, ResourceId: resource.Id, HttpMethod: aws.String(route.Method)})
+			if err != nil {
+				return err
+			}
+			methods[route.Path]--
+		}
+		if _, err := cl.apigw.DeleteDocumentationPart(&apigateway.DeleteDocumentationPartInput{RestApiId: &apigwId, DocumentationPartId: &route.documentationPartID}); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range emptyResources(resources, methods) {
+		log.WithFields(log.Fields{"resource": path}).Info("Deleting resource left without any method")
+		if _, err := cl.apigw.DeleteResource(&apigateway.DeleteResourceInput{RestApiId: &apigwId, ResourceId: resources[path].Id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emptyResources returns the resources left without any method nor child resource, deepest first. The parents of the
+// resources the routes are deleted from are deleted as well once emptied, the resources of the other owners always keep
+// a method or a child resource
+func emptyResources(resources map[string]*apigateway.Resource, methods map[string]int) []string {
+	byID := map[string]string{}
+	children := map[string]int{}
+	for path, resource := range resources {
+		byID[aws.StringValue(resource.Id)] = path
+		if resource.ParentId != nil {
+			children[aws.StringValue(resource.ParentId)]++
+		}
+	}
+
+	parentPath := func(path string) string {
+		if resource, ok := resources[path]; ok && resource.ParentId != nil {
+			return byID[aws.StringValue(resource.ParentId)]
+		}
+		return ""
+	}
+
+	// the methods left on the resources and on their parents
+	left := map[string]int{}
+	for path, count := range methods {
+		left[path] = count
+		for parent := parentPath(path); parent != ""; parent = parentPath(parent) {
+			if _, ok := left[parent]; !ok {
+				left[parent] = len(resources[parent].ResourceMethods)
+			}
+		}
+	}
+
+	var empty []string
+	deleted := map[string]bool{}
+	for found := true; found; {
+		found = false
+		for path, count := range left {
+			resource := resources[path]
+			if path == "/" || deleted[path] || count > 0 || children[aws.StringValue(resource.Id)] > 0 {
+				continue
+			}
+			deleted[path] = true
+			empty = append(empty, path)
+			children[aws.StringValue(resource.ParentId)]--
+			found = true
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(empty)))
+	return empty
+}
+
+// documentRoutes returns the methods of the document
+func documentRoutes(doc swg.Swagger) []OwnedRoute {
+	var routes []OwnedRoute
+	if doc.Paths == nil {
+		return routes
+	}
+	for path, item := range doc.Paths.Paths {
+		for _, operation := range swagger.Operations(item) {
+			routes = append(routes, OwnedRoute{Method: operation.Method, Path: path})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].String() < routes[j].String() })
+	return routes
+}
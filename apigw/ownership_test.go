@@ -0,0 +1,123 @@
+package apigw
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// CheckMark used for unit test highlight.
+	CheckMark = "\u2713"
+
+	// BallotX used for unit test highlight.
+	BallotX = "\u2717"
+)
+
+func TestImportOwnedSwagger_ShouldOnlyTouchTheRoutesOfTheOwner(t *testing.T) {
+
+	t.Logf("Given the REST API holds routes published by the account and billing services")
+	{
+		data, _ := ioutil.ReadFile("../data/swagger.json")
+		var doc swg.Swagger
+		json.Unmarshal(data, &doc)
+
+		owned := []OwnedRoute{
+			{Method: "GET", Path: "/accounts/{accountId}", Owner: "account"},
+			{Method: "DELETE", Path: "/accounts/{accountId}/legacy", Owner: "account"},
+			{Method: "GET", Path: "/invoices", Owner: "billing"},
+		}
+
+		t.Logf("\tWhen the account service publishes its document, its routes no longer published should be stale")
+		{
+			stale := StaleRoutes(doc, owned, "account")
+			if len(stale) == 1 && stale[0].String() == "DELETE /accounts/{accountId}/legacy" {
+				t.Logf("\t\tOnly the account route missing from the document should be deleted %v", CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the account route missing from the document should be deleted, got %v %v", stale, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen the billing service publishes the account document, the account routes should conflict")
+		{
+			conflicts := conflictingRoutes(documentRoutes(doc), owned, "billing")
+			if len(conflicts) == 1 && conflicts[0] == "GET /accounts/{accountId} (account)" {
+				t.Logf("\t\tThe routes of the other owners should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe routes of the other owners should be reported, got %v %v", conflicts, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen recording the ownership, a documentation part should be added for every method")
+		{
+			recorded, err := recordOwnership(data, documentRoutes(doc), "account")
+			parts := documentationParts(recorded)
+			if err == nil && len(parts) == len(documentRoutes(doc)) && strings.Contains(string(recorded), `"x-apigw-pub-owner":"account"`) {
+				t.Logf("\t\tThe owner should be recorded on %d methods %v", len(parts), CheckMark)
+			} else {
+				t.Errorf("\t\tThe owner should be recorded on every method, got %d parts %v %v", len(parts), err, BallotX)
+			}
+		}
+	}
+}
+
+func TestEmptyResources_ShouldKeepTheResourcesWithChildren(t *testing.T) {
+
+	t.Logf("Given the methods of a resource are deleted and its parent has another child")
+	{
+		resources := map[string]*apigateway.Resource{
+			"/":                 {Id: aws.String("root")},
+			"/invoices":         {Id: aws.String("invoices"), ParentId: aws.String("root")},
+			"/invoices/{id}":    {Id: aws.String("invoice"), ParentId: aws.String("invoices")},
+			"/invoices/summary": {Id: aws.String("summary"), ParentId: aws.String("invoices"), ResourceMethods: map[string]*apigateway.Method{"GET": {}}},
+		}
+		methods := map[string]int{"/invoices/{id}": 0}
+
+		t.Logf("\tWhen looking up the empty resources")
+		{
+			empty := emptyResources(resources, methods)
+			if len(empty) == 1 && empty[0] == "/invoices/{id}" {
+				t.Logf("\t\tOnly the leaf resource should be deleted %v", CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the leaf resource should be deleted, got %v %v", empty, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given the methods of a resource two levels below an empty parent are deleted")
+	{
+		resources := map[string]*apigateway.Resource{
+			"/":                     {Id: aws.String("root")},
+			"/accounts":             {Id: aws.String("accounts"), ParentId: aws.String("root")},
+			"/accounts/{id}":        {Id: aws.String("account"), ParentId: aws.String("accounts")},
+			"/accounts/{id}/status": {Id: aws.String("status"), ParentId: aws.String("account")},
+			"/orders":               {Id: aws.String("orders"), ParentId: aws.String("root"), ResourceMethods: map[string]*apigateway.Method{"GET": {}}},
+		}
+		methods := map[string]int{"/accounts/{id}/status": 0}
+
+		t.Logf("\tWhen looking up the empty resources")
+		{
+			empty := emptyResources(resources, methods)
+			expected := []string{"/accounts/{id}/status", "/accounts/{id}", "/accounts"}
+			if reflect.DeepEqual(empty, expected) {
+				t.Logf("\t\tThe emptied parents should be deleted, deepest first %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe emptied parents should be deleted, deepest first, got %v %v", empty, BallotX)
+			}
+		}
+	}
+}
+
+func documentationParts(data []byte) []interface{} {
+	var doc map[string]interface{}
+	json.Unmarshal(data, &doc)
+	documentation, _ := doc[DocumentationExtension].(map[string]interface{})
+	parts, _ := documentation["documentationParts"].([]interface{})
+	return parts
+}
@@ -0,0 +1,196 @@
+package apigw
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AllMethods the key of the settings of all the methods of the stage
+	AllMethods = "*"
+
+	maxCacheTTL      = 3600
+	requestIDContext = "$context.requestId"
+)
+
+var (
+	cacheClusterSizes = []string{"0.5", "1.6", "6.1", "13.5", "28.4", "58.2", "118", "237"}
+	loggingLevels     = []string{"OFF", "ERROR", "INFO"}
+	logDestinations   = []string{"arn:aws:logs:", "arn:aws:firehose:"}
+)
+
+// StageSettings the settings applied to the stage after each deployment, the unset ones are left as they are
+type StageSettings struct {
+	CacheClusterEnabled *bool `yaml:"cacheClusterEnabled"`
+	// CacheClusterSize the cache size in GB: 0.5, 1.6, 6.1, 13.5, 28.4, 58.2, 118 or 237
+	CacheClusterSize string     `yaml:"cacheClusterSize"`
+	TracingEnabled   *bool      `yaml:"tracingEnabled"`
+	AccessLog        *AccessLog `yaml:"accessLog"`
+	// Methods the settings of all the methods (`*`) or of a route (`GET /orders`), the route settings override the ones
+	// of all the methods
+	Methods map[string]MethodSettings `yaml:"methods"`
+}
+
+// AccessLog the access logs of the stage, written in the format to the cloudwatch log group or firehose stream
+type AccessLog struct {
+	DestinationARN string `yaml:"destinationArn"`
+	// Format the log line, exp: {"requestId":"$context.requestId","status":"$context.status"}
+	Format string `yaml:"format"`
+}
+
+// MethodSettings the throttling, caching, execution logging and metrics of the methods
+type MethodSettings struct {
+	ThrottlingBurstLimit *int64   `yaml:"throttlingBurstLimit"`
+	ThrottlingRateLimit  *float64 `yaml:"throttlingRateLimit"`
+	CachingEnabled       *bool    `yaml:"cachingEnabled"`
+	// CacheTTL the seconds a response is cached, up to 3600
+	CacheTTL *int64 `yaml:"cacheTtl"`
+	// LoggingLevel the cloudwatch execution logs: OFF, ERROR or INFO
+	LoggingLevel   string `yaml:"loggingLevel"`
+	DataTrace      *bool  `yaml:"dataTrace"`
+	MetricsEnabled *bool  `yaml:"metricsEnabled"`
+}
+
+// Validate returns the problems of the settings
+func (s StageSettings) Validate() []string {
+	var problems []string
+	if s.CacheClusterSize != "" && !contains(cacheClusterSizes, s.CacheClusterSize) {
+		problems = append(problems, fmt.Sprintf("unsupported cache cluster size %q, expected one of %s", s.CacheClusterSize, strings.Join(cacheClusterSizes, ", ")))
+	}
+	if s.AccessLog != nil {
+		if !hasPrefix(s.AccessLog.DestinationARN, logDestinations) {
+			problems = append(problems, fmt.Sprintf("invalid access log destination %q, expected a log group or firehose arn", s.AccessLog.DestinationARN))
+		}
+		if !strings.Contains(s.AccessLog.Format, requestIDContext) {
+			problems = append(problems, fmt.Sprintf("the access log format must contain %s", requestIDContext))
+		}
+	}
+	for _, key := range s.methodKeys() {
+		settings := s.Methods[key]
+		if _, _, err := methodPath(key); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if settings.ThrottlingBurstLimit != nil && *settings.ThrottlingBurstLimit < 0 || settings.ThrottlingRateLimit != nil && *settings.ThrottlingRateLimit < 0 {
+			problems = append(problems, fmt.Sprintf("the throttling limits of %s must not be negative", key))
+		}
+		if settings.CacheTTL != nil && (*settings.CacheTTL < 0 || *settings.CacheTTL > maxCacheTTL) {
+			problems = append(problems, fmt.Sprintf("the cache ttl of %s must be between 0 and %d seconds", key, maxCacheTTL))
+		}
+		if aws.BoolValue(settings.CachingEnabled) && !aws.BoolValue(s.CacheClusterEnabled) {
+			problems = append(problems, fmt.Sprintf("the caching of %s requires the cache cluster to be enabled", key))
+		}
+		if settings.LoggingLevel != "" && !contains(loggingLevels, strings.ToUpper(settings.LoggingLevel)) {
+			problems = append(problems, fmt.Sprintf("unsupported logging level %q of %s, expected one of %s", settings.LoggingLevel, key, strings.Join(loggingLevels, ", ")))
+		}
+	}
+	return problems
+}
+
+// UpdateStage applies the settings to the deployed stage
+func (cl APIGatewayClient) UpdateStage(apigwId string, stage string, settings StageSettings) error {
+	patches := settings.patches()
+	if len(patches) == 0 {
+		return nil
+	}
+	log.WithFields(log.Fields{"stage": stage, "API GatewayId": apigwId}).Info("Updating stage settings")
+	_, err := cl.apigw.UpdateStage(&apigateway.UpdateStageInput{RestApiId: aws.String(apigwId), StageName: aws.String(stage), PatchOperations: patches})
+	return err
+}
+
+// patches returns the operations setting the configured settings of the stage
+func (s StageSettings) patches() []*apigateway.PatchOperation {
+	var patches []*apigateway.PatchOperation
+	replace := func(path string, value string) {
+		patches = append(patches, &apigateway.PatchOperation{Op: aws.String(apigateway.OpReplace), Path: aws.String(path), Value: aws.String(value)})
+	}
+	if s.CacheClusterEnabled != nil {
+		replace("/cacheClusterEnabled", strconv.FormatBool(*s.CacheClusterEnabled))
+	}
+	if s.CacheClusterSize != "" {
+		replace("/cacheClusterSize", s.CacheClusterSize)
+	}
+	if s.TracingEnabled != nil {
+		replace("/tracingEnabled", strconv.FormatBool(*s.TracingEnabled))
+	}
+	if s.AccessLog != nil {
+		replace("/accessLogSettings/destinationArn", s.AccessLog.DestinationARN)
+		replace("/accessLogSettings/format", s.AccessLog.Format)
+	}
+
+	for _, key := range s.methodKeys() {
+		settings := s.Methods[key]
+		resource, method, _ := methodPath(key)
+		prefix := fmt.Sprintf("/%s/%s", resource, method)
+		if settings.ThrottlingBurstLimit != nil {
+			replace(prefix+"/throttling/burstLimit", strconv.FormatInt(*settings.ThrottlingBurstLimit, 10))
+		}
+		if settings.ThrottlingRateLimit != nil {
+			replace(prefix+"/throttling/rateLimit", strconv.FormatFloat(*settings.ThrottlingRateLimit, 'f', -1, 64))
+		}
+		if settings.CachingEnabled != nil {
+			replace(prefix+"/caching/enabled", strconv.FormatBool(*settings.CachingEnabled))
+		}
+		if settings.CacheTTL != nil {
+			replace(prefix+"/caching/ttlInSeconds", strconv.FormatInt(*settings.CacheTTL, 10))
+		}
+		if settings.LoggingLevel != "" {
+			replace(prefix+"/logging/loglevel", strings.ToUpper(settings.LoggingLevel))
+		}
+		if settings.DataTrace != nil {
+			replace(prefix+"/logging/dataTrace", strconv.FormatBool(*settings.DataTrace))
+		}
+		if settings.MetricsEnabled != nil {
+			replace(prefix+"/metrics/enabled", strconv.FormatBool(*settings.MetricsEnabled))
+		}
+	}
+	return patches
+}
+
+// methodKeys the keys of the method settings, all the methods first so that the routes override them
+func (s StageSettings) methodKeys() []string {
+	var keys []string
+	for key := range s.Methods {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == AllMethods) != (keys[j] == AllMethods) {
+			return keys[i] == AllMethods
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// methodPath returns the resource path, its slashes escaped, and the http method of the settings key
+func methodPath(key string) (string, string, error) {
+	if key == AllMethods {
+		return "*", "*", nil
+	}
+	parts := strings.Fields(key)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "/") {
+		return "", "", fmt.Errorf("invalid method settings key %q, expected %s or a route exp: GET /orders", key, AllMethods)
+	}
+	method := strings.ToUpper(parts[0])
+	if method != "*" && !contains(httpMethods, method) {
+		return "", "", fmt.Errorf("unsupported method %q of the method settings %q", parts[0], key)
+	}
+	return strings.Replace(parts[1], "/", "~1", -1), method, nil
+}
+
+var httpMethods = []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodOptions, http.MethodHead, http.MethodPatch}
+
+func hasPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package apigw
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestStageSettings_ShouldPatchTheConfiguredSettingsOnly(t *testing.T) {
+
+	t.Logf("Given the settings of all the methods and a cached route")
+	{
+		settings := StageSettings{
+			CacheClusterEnabled: aws.Bool(true),
+			CacheClusterSize:    "0.5",
+			TracingEnabled:      aws.Bool(true),
+			AccessLog:           &AccessLog{DestinationARN: "arn:aws:logs:eu-west-1:123456789012:log-group:orders", Format: `{"requestId":"$context.requestId"}`},
+			Methods: map[string]MethodSettings{
+				"GET /orders/{orderId}": {CachingEnabled: aws.Bool(true), CacheTTL: aws.Int64(300)},
+				AllMethods:              {ThrottlingBurstLimit: aws.Int64(50), ThrottlingRateLimit: aws.Float64(25.5), LoggingLevel: "error", MetricsEnabled: aws.Bool(true)},
+			},
+		}
+
+		t.Logf("\tWhen patching the stage, the settings of all the methods should come first")
+		{
+			var patches []string
+			for _, patch := range settings.patches() {
+				patches = append(patches, *patch.Path+"="+*patch.Value)
+			}
+			expected := []string{
+				"/cacheClusterEnabled=true",
+				"/cacheClusterSize=0.5",
+				"/tracingEnabled=true",
+				"/accessLogSettings/destinationArn=arn:aws:logs:eu-west-1:123456789012:log-group:orders",
+				`/accessLogSettings/format={"requestId":"$context.requestId"}`,
+				"/*/*/throttling/burstLimit=50",
+				"/*/*/throttling/rateLimit=25.5",
+				"/*/*/logging/loglevel=ERROR",
+				"/*/*/metrics/enabled=true",
+				"/~1orders~1{orderId}/GET/caching/enabled=true",
+				"/~1orders~1{orderId}/GET/caching/ttlInSeconds=300",
+			}
+			if reflect.DeepEqual(patches, expected) {
+				t.Logf("\t\tThe configured settings should be patched %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe configured settings should be patched, got %v %v", patches, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given invalid stage settings")
+	{
+		settings := StageSettings{
+			CacheClusterSize: "2",
+			AccessLog:        &AccessLog{DestinationARN: "orders", Format: "$context.status"},
+			Methods: map[string]MethodSettings{
+				"/orders":     {LoggingLevel: "DEBUG"},
+				"GET /orders": {CachingEnabled: aws.Bool(true), CacheTTL: aws.Int64(7200)},
+			},
+		}
+
+		t.Logf("\tWhen validating the settings, all the problems should be reported")
+		{
+			if problems := settings.Validate(); len(problems) == 7 {
+				t.Logf("\t\tThe problems should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe problems should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
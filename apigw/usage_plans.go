@@ -0,0 +1,412 @@
+package apigw
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// APIKeysFile the csv file of the api keys provisioned along with the usage plans
+	APIKeysFile = "API_KEYS_FILE"
+
+	apiKeyType = "API_KEY"
+)
+
+var quotaPeriods = []string{apigateway.QuotaPeriodTypeDay, apigateway.QuotaPeriodTypeWeek, apigateway.QuotaPeriodTypeMonth}
+
+// UsagePlans the usage plans and api keys provisioned after the deployment, the plans are associated with the stage
+type UsagePlans struct {
+	Plans []UsagePlan `yaml:"plans"`
+	Keys  []APIKey    `yaml:"keys"`
+	// KeysFile a csv file of more keys with the Name,Key,Description,Enabled,UsagePlans columns, the plans separated by
+	// commas
+	KeysFile string `yaml:"keysFile"`
+}
+
+// UsagePlan the limits of the callers of the keys of the plan, keyed by name
+type UsagePlan struct {
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description"`
+	Throttle    *Throttle `yaml:"throttle"`
+	Quota       *Quota    `yaml:"quota"`
+}
+
+// Throttle the steady rate of requests per second and the burst allowed
+type Throttle struct {
+	RateLimit  float64 `yaml:"rateLimit"`
+	BurstLimit int64   `yaml:"burstLimit"`
+}
+
+// Quota the requests allowed per period: DAY, WEEK or MONTH
+type Quota struct {
+	Limit  int64  `yaml:"limit"`
+	Period string `yaml:"period"`
+	Offset int64  `yaml:"offset"`
+}
+
+// APIKey a key keyed by name, API Gateway generates the value when none is set
+type APIKey struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value"`
+	Description string `yaml:"description"`
+	// Disabled keys are rejected by API Gateway
+	Disabled bool `yaml:"disabled"`
+	// UsagePlans the names of the plans of the key
+	UsagePlans []string `yaml:"usagePlans"`
+}
+
+func (p UsagePlans) isEmpty() bool {
+	return len(p.Plans) == 0 && len(p.Keys) == 0 && p.KeysFile == ""
+}
+
+// Validate returns the problems of the plans and keys, the keys must reference declared plans
+func (p UsagePlans) Validate() []string {
+	var problems []string
+	plans := map[string]bool{}
+	for _, plan := range p.Plans {
+		problems = append(problems, plan.Validate()...)
+		if plans[plan.Name] {
+			problems = append(problems, fmt.Sprintf("the usage plan %q is declared more than once", plan.Name))
+		}
+		plans[plan.Name] = true
+	}
+	declared, err := p.keys()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("the api keys file (%s) %q cannot be read: %s", APIKeysFile, p.KeysFile, err))
+	}
+	keys := map[string]bool{}
+	for _, key := range declared {
+		if key.Name == "" {
+			problems = append(problems, "the name of the api key is required")
+		}
+		if keys[key.Name] {
+			problems = append(problems, fmt.Sprintf("the api key %q is declared more than once", key.Name))
+		}
+		keys[key.Name] = true
+		for _, plan := range key.UsagePlans {
+			if !plans[plan] {
+				problems = append(problems, fmt.Sprintf("the api key %q references the undeclared usage plan %q", key.Name, plan))
+			}
+		}
+	}
+	return problems
+}
+
+// keys returns the declared keys followed by the ones of the keys file
+func (p UsagePlans) keys() ([]APIKey, error) {
+	if p.KeysFile == "" {
+		return p.Keys, nil
+	}
+	file, err := os.Open(p.KeysFile)
+	if err != nil {
+		return p.Keys, err
+	}
+	defer file.Close()
+	imported, err := ReadAPIKeys(file)
+	if err != nil {
+		return p.Keys, err
+	}
+	return append(append([]APIKey{}, p.Keys...), imported...), nil
+}
+
+// Validate returns the problems of the plan
+func (p UsagePlan) Validate() []string {
+	var problems []string
+	if p.Name == "" {
+		problems = append(problems, "the name of the usage plan is required")
+	}
+	if p.Throttle != nil && (p.Throttle.RateLimit <= 0 || p.Throttle.BurstLimit <= 0) {
+		problems = append(problems, fmt.Sprintf("the throttle limits of the usage plan %q must be positive", p.Name))
+	}
+	if p.Quota != nil {
+		if p.Quota.Limit <= 0 {
+			problems = append(problems, fmt.Sprintf("the quota limit of the usage plan %q must be positive", p.Name))
+		}
+		if !contains(quotaPeriods, strings.ToUpper(p.Quota.Period)) {
+			problems = append(problems, fmt.Sprintf("unsupported quota period %q of the usage plan %q, expected one of %s", p.Quota.Period, p.Name, strings.Join(quotaPeriods, ", ")))
+		}
+	}
+	return problems
+}
+
+// ReadAPIKeys reads the keys of a csv file with the Name,Key,Description,Enabled,UsagePlans columns, the header names the
+// columns in any order and only the Name column is required
+func ReadAPIKeys(reader io.Reader) ([]APIKey, error) {
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for i, column := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("the api keys file has no Name column")
+	}
+	value := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var keys []APIKey
+	for line, row := range rows[1:] {
+		key := APIKey{
+			Name:        value(row, "name"),
+			Value:       value(row, "key"),
+			Description: value(row, "description"),
+		}
+		if key.Name == "" {
+			return nil, fmt.Errorf("the api key of line %d has no name", line+2)
+		}
+		if enabled := value(row, "enabled"); enabled != "" {
+			parsed, err := strconv.ParseBool(enabled)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enabled value %q of the api key %s", enabled, key.Name)
+			}
+			key.Disabled = !parsed
+		}
+		for _, plan := range strings.Split(value(row, "usageplans"), ",") {
+			if plan = strings.TrimSpace(plan); plan != "" {
+				key.UsagePlans = append(key.UsagePlans, plan)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ProvisionUsagePlans - Function
+// Creates or updates the usage plans and api keys, keyed by name, associates the plans with the stage and the keys with
+// their plans. The plans and keys created outside of the configuration are left untouched.
+func (cl APIGatewayClient) ProvisionUsagePlans(apigwId string, stage string, plans UsagePlans) error {
+	if plans.isEmpty() {
+		return nil
+	}
+	keys, err := plans.keys()
+	if err != nil {
+		return err
+	}
+
+	planIDs, err := cl.provisionPlans(apigwId, stage, plans.Plans)
+	if err != nil {
+		return err
+	}
+	existing, err := cl.apiKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		keyID, err := cl.provisionKey(key, existing[key.Name])
+		if err != nil {
+			return err
+		}
+		for _, plan := range key.UsagePlans {
+			planID, ok := planIDs[plan]
+			if !ok {
+				return fmt.Errorf("the api key %s references the undeclared usage plan %s", key.Name, plan)
+			}
+			if err := cl.associateKey(planID, keyID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// provisionPlans creates or updates the plans and returns their ids keyed by name
+func (cl APIGatewayClient) provisionPlans(apigwId string, stage string, plans []UsagePlan) (map[string]string, error) {
+	existing := map[string]*apigateway.UsagePlan{}
+	input := apigateway.GetUsagePlansInput{Limit: aws.Int64(pageSize)}
+	for {
+		output, err := cl.apigw.GetUsagePlans(&input)
+		if err != nil {
+			return nil, err
+		}
+		for _, plan := range output.Items {
+			existing[aws.StringValue(plan.Name)] = plan
+		}
+		if output.Position == nil || *output.Position == "" {
+			break
+		}
+		input.Position = output.Position
+	}
+
+	ids := map[string]string{}
+	for _, plan := range plans {
+		current, ok := existing[plan.Name]
+		if !ok {
+			log.WithFields(log.Fields{"plan": plan.Name, "stage": stage}).Info("Creating usage plan")
+			created, err := cl.apigw.CreateUsagePlan(&apigateway.CreateUsagePlanInput{
+				Name:        aws.String(plan.Name),
+				Description: aws.String(plan.Description),
+				Throttle:    plan.throttle(),
+				Quota:       plan.quota(),
+				ApiStages:   []*apigateway.ApiStage{{ApiId: aws.String(apigwId), Stage: aws.String(stage)}},
+			})
+			if err != nil {
+				return nil, err
+			}
+			ids[plan.Name] = aws.StringValue(created.Id)
+			continue
+		}
+
+		ids[plan.Name] = aws.StringValue(current.Id)
+		if patches := usagePlanPatches(current, plan, apigwId, stage); len(patches) > 0 {
+			log.WithFields(log.Fields{"plan": plan.Name, "stage": stage}).Info("Updating usage plan")
+			_, err := cl.apigw.UpdateUsagePlan(&apigateway.UpdateUsagePlanInput{UsagePlanId: current.Id, PatchOperations: patches})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+// usagePlanPatches returns the operations updating the existing plan to the declared one and associating it with the stage
+func usagePlanPatches(current *apigateway.UsagePlan, plan UsagePlan, apigwId string, stage string) []*apigateway.PatchOperation {
+	var patches []*apigateway.PatchOperation
+	replace := func(path string, value string) {
+		patches = append(patches, &apigateway.PatchOperation{Op: aws.String(apigateway.OpReplace), Path: aws.String(path), Value: aws.String(value)})
+	}
+	if aws.StringValue(current.Description) != plan.Description {
+		replace("/description", plan.Description)
+	}
+	if throttle := plan.throttle(); throttle != nil && (current.Throttle == nil ||
+		aws.Float64Value(current.Throttle.RateLimit) != *throttle.RateLimit || aws.Int64Value(current.Throttle.BurstLimit) != *throttle.BurstLimit) {
+		replace("/throttle/rateLimit", strconv.FormatFloat(*throttle.RateLimit, 'f', -1, 64))
+		replace("/throttle/burstLimit", strconv.FormatInt(*throttle.BurstLimit, 10))
+	}
+	if quota := plan.quota(); quota != nil && (current.Quota == nil || aws.Int64Value(current.Quota.Limit) != *quota.Limit ||
+		aws.StringValue(current.Quota.Period) != *quota.Period || aws.Int64Value(current.Quota.Offset) != *quota.Offset) {
+		replace("/quota/limit", strconv.FormatInt(*quota.Limit, 10))
+		replace("/quota/period", *quota.Period)
+		replace("/quota/offset", strconv.FormatInt(*quota.Offset, 10))
+	}
+	for _, apiStage := range current.ApiStages {
+		if aws.StringValue(apiStage.ApiId) == apigwId && aws.StringValue(apiStage.Stage) == stage {
+			return patches
+		}
+	}
+	return append(patches, &apigateway.PatchOperation{
+		Op:    aws.String(apigateway.OpAdd),
+		Path:  aws.String("/apiStages"),
+		Value: aws.String(fmt.Sprintf("%s:%s", apigwId, stage)),
+	})
+}
+
+func (p UsagePlan) throttle() *apigateway.ThrottleSettings {
+	if p.Throttle == nil {
+		return nil
+	}
+	return &apigateway.ThrottleSettings{RateLimit: aws.Float64(p.Throttle.RateLimit), BurstLimit: aws.Int64(p.Throttle.BurstLimit)}
+}
+
+func (p UsagePlan) quota() *apigateway.QuotaSettings {
+	if p.Quota == nil {
+		return nil
+	}
+	return &apigateway.QuotaSettings{
+		Limit:  aws.Int64(p.Quota.Limit),
+		Period: aws.String(strings.ToUpper(p.Quota.Period)),
+		Offset: aws.Int64(p.Quota.Offset),
+	}
+}
+
+// apiKeys returns the existing api keys keyed by name
+func (cl APIGatewayClient) apiKeys() (map[string]*apigateway.ApiKey, error) {
+	keys := map[string]*apigateway.ApiKey{}
+	input := apigateway.GetApiKeysInput{Limit: aws.Int64(pageSize)}
+	for {
+		output, err := cl.apigw.GetApiKeys(&input)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range output.Items {
+			keys[aws.StringValue(key.Name)] = key
+		}
+		if output.Position == nil || *output.Position == "" {
+			return keys, nil
+		}
+		input.Position = output.Position
+	}
+}
+
+// provisionKey creates the key or updates its description and status, the value of an existing key is never changed
+func (cl APIGatewayClient) provisionKey(key APIKey, current *apigateway.ApiKey) (string, error) {
+	if current == nil {
+		log.WithFields(log.Fields{"key": key.Name}).Info("Creating api key")
+		input := apigateway.CreateApiKeyInput{
+			Name:        aws.String(key.Name),
+			Description: aws.String(key.Description),
+			Enabled:     aws.Bool(!key.Disabled),
+		}
+		if key.Value != "" {
+			input.Value = aws.String(key.Value)
+		}
+		created, err := cl.apigw.CreateApiKey(&input)
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(created.Id), nil
+	}
+
+	var patches []*apigateway.PatchOperation
+	if aws.StringValue(current.Description) != key.Description {
+		patches = append(patches, &apigateway.PatchOperation{Op: aws.String(apigateway.OpReplace), Path: aws.String("/description"), Value: aws.String(key.Description)})
+	}
+	if aws.BoolValue(current.Enabled) == key.Disabled {
+		patches = append(patches, &apigateway.PatchOperation{Op: aws.String(apigateway.OpReplace), Path: aws.String("/enabled"), Value: aws.String(strconv.FormatBool(!key.Disabled))})
+	}
+	if len(patches) > 0 {
+		log.WithFields(log.Fields{"key": key.Name}).Info("Updating api key")
+		if _, err := cl.apigw.UpdateApiKey(&apigateway.UpdateApiKeyInput{ApiKey: current.Id, PatchOperations: patches}); err != nil {
+			return "", err
+		}
+	}
+	return aws.StringValue(current.Id), nil
+}
+
+// associateKey adds the key to the plan unless it already belongs to it
+func (cl APIGatewayClient) associateKey(planID string, keyID string) error {
+	_, err := cl.apigw.GetUsagePlanKey(&apigateway.GetUsagePlanKeyInput{UsagePlanId: aws.String(planID), KeyId: aws.String(keyID)})
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return err
+	}
+	_, err = cl.apigw.CreateUsagePlanKey(&apigateway.CreateUsagePlanKeyInput{
+		UsagePlanId: aws.String(planID),
+		KeyId:       aws.String(keyID),
+		KeyType:     aws.String(apiKeyType),
+	})
+	return err
+}
+
+func isNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == apigateway.ErrCodeNotFoundException
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package apigw
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+func TestReadAPIKeys_ShouldReadTheKeysOfTheCSVFile(t *testing.T) {
+
+	t.Logf("Given a csv file of two partner keys")
+	{
+		file, _ := os.Open("../data/api_keys.csv")
+		defer file.Close()
+
+		t.Logf("\tWhen reading the file, the keys should be returned with their plans")
+		{
+			keys, err := ReadAPIKeys(file)
+			if err != nil {
+				t.Fatalf("\t\tFailed to read the keys %v %v", err, BallotX)
+			}
+			expected := []APIKey{
+				{Name: "acme", Value: "acme-0123456789abcdefghij", Description: "Acme partner", UsagePlans: []string{"gold", "bronze"}},
+				{Name: "globex", Description: "Globex partner", Disabled: true, UsagePlans: []string{"bronze"}},
+			}
+			if reflect.DeepEqual(keys, expected) {
+				t.Logf("\t\tThe keys should be read %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe keys should be read, got %+v %v", keys, BallotX)
+			}
+		}
+	}
+}
+
+func TestUsagePlanPatches_ShouldUpdateTheChangedLimitsAndAssociateTheStage(t *testing.T) {
+
+	t.Logf("Given a usage plan deployed to another stage with a lower quota")
+	{
+		current := &apigateway.UsagePlan{
+			Name:      aws.String("gold"),
+			Throttle:  &apigateway.ThrottleSettings{RateLimit: aws.Float64(100), BurstLimit: aws.Int64(200)},
+			Quota:     &apigateway.QuotaSettings{Limit: aws.Int64(1000), Period: aws.String("DAY"), Offset: aws.Int64(0)},
+			ApiStages: []*apigateway.ApiStage{{ApiId: aws.String("a1b2c3"), Stage: aws.String("dev")}},
+		}
+		plan := UsagePlan{
+			Name:     "gold",
+			Throttle: &Throttle{RateLimit: 100, BurstLimit: 200},
+			Quota:    &Quota{Limit: 5000, Period: "day"},
+		}
+
+		t.Logf("\tWhen patching the plan, the quota should be replaced and the stage added")
+		{
+			var paths []string
+			for _, patch := range usagePlanPatches(current, plan, "a1b2c3", "prod") {
+				paths = append(paths, *patch.Op+" "+*patch.Path+" "+*patch.Value)
+			}
+			expected := []string{"replace /quota/limit 5000", "replace /quota/period DAY", "replace /quota/offset 0", "add /apiStages a1b2c3:prod"}
+			if reflect.DeepEqual(paths, expected) {
+				t.Logf("\t\tOnly the changes should be patched %v", CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the changes should be patched, got %v %v", paths, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given usage plans with invalid limits and a key of an undeclared plan")
+	{
+		plans := UsagePlans{
+			Plans: []UsagePlan{{Name: "gold", Throttle: &Throttle{RateLimit: 0, BurstLimit: 10}, Quota: &Quota{Limit: 10, Period: "YEAR"}}},
+			Keys:  []APIKey{{Name: "acme", UsagePlans: []string{"silver"}}},
+		}
+
+		t.Logf("\tWhen validating the plans, all the problems should be reported")
+		{
+			if problems := plans.Validate(); len(problems) == 3 {
+				t.Logf("\t\tThe problems should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe problems should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
+
+func TestUsagePlans_ShouldValidateTheKeysOfTheCSVFile(t *testing.T) {
+
+	t.Logf("Given a csv file of keys referencing the gold and bronze plans, only the gold plan being declared")
+	{
+		plans := UsagePlans{Plans: []UsagePlan{{Name: "gold"}}, KeysFile: "../data/api_keys.csv"}
+
+		t.Logf("\tWhen validating the plans, the keys of the undeclared plan should be reported")
+		{
+			problems := plans.Validate()
+			if len(problems) == 2 && strings.Contains(problems[0], `"bronze"`) && strings.Contains(problems[1], `"bronze"`) {
+				t.Logf("\t\tThe keys of the bronze plan should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe keys of the bronze plan should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given a csv file without the Name column")
+	{
+		dir, _ := ioutil.TempDir("", "api-keys")
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "api_keys.csv")
+		ioutil.WriteFile(file, []byte("Key,UsagePlans\nacme-0123456789abcdefghij,gold\n"), 0644)
+		plans := UsagePlans{Plans: []UsagePlan{{Name: "gold"}}, KeysFile: file}
+
+		t.Logf("\tWhen validating the plans, the file should be reported")
+		{
+			if problems := plans.Validate(); len(problems) == 1 && strings.Contains(problems[0], APIKeysFile) {
+				t.Logf("\t\tThe invalid file should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe invalid file should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
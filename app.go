@@ -1,17 +1,12 @@
 package main
 
 import (
-	"github.com/akhettar/apigw-pub/apigw"
-	"github.com/akhettar/apigw-pub/swagger"
-	"github.com/akhettar/apigw-pub/utils"
+	"flag"
+	"fmt"
+	"github.com/akhettar/apigw-pub/config"
 	log "github.com/sirupsen/logrus"
 	"os"
-)
-
-const (
-	StageNameVarKey = "STAGE_NAME"
-	APIGatewayIDKey = "API_GATEWAY_ID"
-	SwaggerUrl      = "SWAGGER_URL"
+	"strings"
 )
 
 func init() {
@@ -29,31 +24,47 @@ func init() {
 }
 
 func main() {
-	client := swagger.NewSwaggerClient(utils.RetrieveEnvVar(SwaggerUrl))
-	doc, err := client.FetchSwagger()
-	if err != nil {
-		log.WithFields(log.Fields{"Swagger Url": utils.RetrieveEnvVar(SwaggerUrl)}).Fatal("Failed to retrieve swagger document")
-	}
-	renderedSwag, err := client.RenderSwagger(doc)
-	if err != nil {
-		log.WithFields(log.Fields{"Error": err}).Fatal("Failed to render swagger document")
+	args := os.Args[1:]
+
+	// running without a sub command publishes, as the publisher has always done
+	name := DefaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	} else if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		name = "help"
 	}
 
-	// Import swagger
-	apigwClient := apigw.NewAPIGatewayClient()
-	report, err := apigwClient.ImportSwagger(renderedSwag, utils.RetrieveEnvVar(APIGatewayIDKey))
+	if name == "help" {
+		printUsage()
+		return
+	}
 
-	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Fatal("Failed to publish the swagger doc")
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := runCommand(cmd, args)
+		if err == flag.ErrHelp {
+			return
+		}
+		if validationErr, ok := err.(config.ValidationError); ok {
+			fmt.Fprintln(os.Stderr, validationErr)
+			os.Exit(2)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"command": name}).Fatal(err)
+		}
+		return
 	}
-	log.Info(report)
-
-	// Deploy API
-	deployment, err := apigwClient.CreateDeployment(utils.RetrieveEnvVar(StageNameVarKey),
-		utils.RetrieveEnvVar(APIGatewayIDKey))
-	log.Info(deployment)
-	if err != nil {
-		log.WithFields(log.Fields{"Error": err}).Fatal("Failed to deploy the newly created resources  ❌")
+
+	printUsage()
+	log.WithFields(log.Fields{"command": name}).Fatal("Unknown command")
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: apigw-pub <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.description)
 	}
-	log.Info("Swagger import and deployment is successfully completed ✅")
+	fmt.Fprintf(os.Stderr, "\nRun 'apigw-pub <command> --help' for the command flags. Every flag falls back to its environment variable.\n")
 }
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/akhettar/apigw-pub/apigw"
+	"github.com/akhettar/apigw-pub/config"
+	"github.com/akhettar/apigw-pub/diff"
+	"github.com/akhettar/apigw-pub/swagger"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	swg "github.com/go-openapi/spec"
+	log "github.com/sirupsen/logrus"
+)
+
+const DefaultCommand = "publish"
+
+// reports the summaries and diffs are written to, moved to stderr when the rendered document is written to stdout
+var reports io.Writer = os.Stdout
+
+// command a sub command of the publisher
+type command struct {
+	name        string
+	description string
+	groups      []config.Group
+	bind        func(fs *flag.FlagSet, cfg *config.Config)
+	run         func(cfg config.Config) error
+}
+
+var commands = []command{
+	{
+		name:        "render",
+		description: "Fetches and renders the swagger document without touching API Gateway",
+		groups:      []config.Group{config.SourceGroup, config.OutputGroup},
+		bind: func(fs *flag.FlagSet, cfg *config.Config) {
+			cfg.DryRun = true
+		},
+		run: renderCommand,
+	},
+	{
+		name:        "import",
+		description: "Renders and imports the swagger document into API Gateway",
+		groups:      []config.Group{config.SourceGroup, config.GatewayGroup},
+		bind:        bindDiff,
+		run:         importCommand,
+	},
+	{
+		name:        "deploy",
+		description: "Deploys the imported resources to the stage",
+		groups:      []config.Group{config.GatewayGroup, config.StageGroup},
+		run:         deployCommand,
+	},
+	{
+		name:        "promote",
+		description: "Checks the health of the stage and promotes its canary deployment to all the traffic",
+		groups:      []config.Group{config.GatewayGroup, config.StageGroup},
+		run:         promoteCommand,
+	},
+	{
+		name:        "rollback",
+		description: "Removes the canary deployment of the stage, its deployment serves all the traffic again",
+		groups:      []config.Group{config.GatewayGroup, config.StageGroup},
+		run:         rollbackCommand,
+	},
+	{
+		name:        "publish",
+		description: "Renders, imports and deploys the swagger document (default command)",
+		groups:      []config.Group{config.SourceGroup, config.GatewayGroup, config.StageGroup, config.OutputGroup},
+		bind: func(fs *flag.FlagSet, cfg *config.Config) {
+			bindDiff(fs, cfg)
+			fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "stops after rendering, nothing is sent to API Gateway (env DRY_RUN)")
+		},
+		run: publishCommand,
+	},
+	{
+		name:        "diff",
+		description: "Compares the rendered swagger document with the one deployed to the stage",
+		groups:      []config.Group{config.SourceGroup, config.GatewayGroup, config.StageGroup, config.DiffGroup},
+		run:         diffCommand,
+	},
+	{
+		name:        "validate",
+		description: "Validates the configuration and checks the swagger document can be fetched and rendered",
+		groups:      []config.Group{config.SourceGroup, config.GatewayGroup, config.StageGroup},
+		run:         validateCommand,
+	},
+}
+
+// the diff is optional on import, the stage is only required when it is enabled
+func bindDiff(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Diff.Enabled, "diff", cfg.Diff.Enabled, "diffs against the deployed stage before the import (env DIFF_ENABLED)")
+	if fs.Lookup("stage") == nil {
+		cfg.Bind(fs, config.StageGroup)
+	}
+	cfg.Bind(fs, config.DiffGroup)
+}
+
+// runCommand parses the command flags, validates the configuration and runs the command
+func runCommand(cmd command, args []string) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: apigw-pub %s [flags]\n\n%s\n\nFlags:\n", cmd.name, cmd.description)
+		fs.PrintDefaults()
+	}
+
+	// the configuration file provides the defaults of all the other flags
+	path, environment := config.Locate(args)
+	fs.String("config", path, fmt.Sprintf("the configuration file, defaults to %v in the working directory (env %s)", config.DefaultFiles, config.ConfigFile))
+	fs.String("environment", environment, fmt.Sprintf("the environment overlay of the configuration file applied (env %s)", config.Environment))
+	cfg := config.FromEnv()
+	if !isHelp(args) {
+		loaded, err := config.Load(path, environment)
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+	}
+
+	cfg.Bind(fs, cmd.groups...)
+	if cmd.bind != nil {
+		cmd.bind(fs, &cfg)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// the rendered document is written to stdout, logs and reports are moved out of its way
+	if cfg.DryRun && cfg.Output == config.StdOutput && hasGroup(cmd.groups, config.OutputGroup) {
+		log.SetOutput(os.Stderr)
+		reports = os.Stderr
+	}
+
+	groups := cmd.groups
+	if cfg.Diff.Enabled {
+		groups = append(groups, config.StageGroup, config.DiffGroup)
+	}
+	if err := cfg.Validate(groups...); err != nil {
+		return err
+	}
+	return cmd.run(cfg)
+}
+
+func isHelp(args []string) bool {
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" || arg == "-help" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasGroup(groups []config.Group, group config.Group) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+func renderCommand(cfg config.Config) error {
+	renderedSwag, report, err := render(cfg)
+	if err != nil {
+		return err
+	}
+	return writeRendered(renderedSwag, report, cfg.Output)
+}
+
+func importCommand(cfg config.Config) error {
+	renderedSwag, _, err := render(cfg)
+	if err != nil {
+		return err
+	}
+	return importSwagger(cfg, newAPIGatewayClient(cfg), renderedSwag)
+}
+
+func deployCommand(cfg config.Config) error {
+	return deploy(cfg, newAPIGatewayClient(cfg))
+}
+
+func promoteCommand(cfg config.Config) error {
+	if cfg.Canary.HealthCheck.URL != "" {
+		if err := apigw.CheckHealth(cfg.Canary.HealthCheck); err != nil {
+			return fmt.Errorf("the canary is unhealthy, it has not been promoted: %w", err)
+		}
+	}
+	if err := newAPIGatewayClient(cfg).PromoteCanary(cfg.APIGateway.Stage, cfg.APIGateway.ID); err != nil {
+		return err
+	}
+	log.Info("Canary promoted ✅")
+	return nil
+}
+
+func rollbackCommand(cfg config.Config) error {
+	if err := newAPIGatewayClient(cfg).RollbackCanary(cfg.APIGateway.Stage, cfg.APIGateway.ID); err != nil {
+		return err
+	}
+	log.Info("Canary rolled back ✅")
+	return nil
+}
+
+func publishCommand(cfg config.Config) error {
+	renderedSwag, report, err := render(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Dry run stops after rendering, nothing is sent to API Gateway
+	if cfg.DryRun {
+		if cfg.Diff.Enabled {
+			changes, err := diffDeployedSwagger(cfg, newAPIGatewayClient(cfg), renderedSwag)
+			if err != nil {
+				return err
+			}
+			if err := checkRemovedRoutes(cfg, changes); err != nil {
+				return err
+			}
+		}
+		if err := writeRendered(renderedSwag, report, cfg.Output); err != nil {
+			return err
+		}
+		log.Info("Dry run completed, nothing has been published to API Gateway ✅")
+		return nil
+	}
+
+	apigwClient := newAPIGatewayClient(cfg)
+	if err := importSwagger(cfg, apigwClient, renderedSwag); err != nil {
+		return err
+	}
+	if err := deploy(cfg, apigwClient); err != nil {
+		return err
+	}
+	log.Info("Swagger import and deployment is successfully completed ✅")
+	return nil
+}
+
+func diffCommand(cfg config.Config) error {
+	renderedSwag, _, err := render(cfg)
+	if err != nil {
+		return err
+	}
+	changes, err := diffDeployedSwagger(cfg, newAPIGatewayClient(cfg), renderedSwag)
+	if err != nil {
+		return err
+	}
+	return checkRemovedRoutes(cfg, changes)
+}
+
+func validateCommand(cfg config.Config) error {
+	if _, _, err := render(cfg); err != nil {
+		return err
+	}
+	log.Info("Configuration and swagger document are valid ✅")
+	return nil
+}
+
+func newAPIGatewayClient(cfg config.Config) apigw.APIGatewayClient {
+	return apigw.NewAPIGatewayClient(cfg.Region, cfg.AssumeRole)
+}
+
+// render fetches the vanilla swagger and renders it with the AWS extensions, the documents of the services are merged
+// when several services are published to the REST API
+func render(cfg config.Config) ([]byte, swagger.RenderReport, error) {
+	if len(cfg.Services) > 0 {
+		return swagger.RenderServices(cfg.APIGateway.Name, cfg.RenderServices())
+	}
+	client := swagger.NewSwaggerClientWithOptions(cfg.SwaggerURL, cfg.RenderOptions())
+	doc, err := client.FetchSwagger()
+	if err != nil {
+		return nil, swagger.RenderReport{}, fmt.Errorf("failed to retrieve the swagger document %s: %w", cfg.SwaggerURL, err)
+	}
+	return client.RenderSwaggerWithReport(doc)
+}
+
+// importSwagger imports the rendered swagger, after comparing it with the deployed stage when the diff is enabled
+func importSwagger(cfg config.Config, apigwClient apigw.APIGatewayClient, renderedSwag []byte) error {
+	if cfg.Diff.Enabled {
+		changes, err := diffDeployedSwagger(cfg, apigwClient, renderedSwag)
+		if err != nil {
+			return err
+		}
+		if err := checkRemovedRoutes(cfg, changes); err != nil {
+			return err
+		}
+	}
+
+	restApi, err := importDocument(cfg, apigwClient, renderedSwag)
+	if err != nil {
+		return fmt.Errorf("failed to publish the swagger document: %w", err)
+	}
+	log.Info(restApi)
+	return nil
+}
+
+// importDocument imports the document with the configured import mode, the routes of the other owners are left
+// untouched on merge
+func importDocument(cfg config.Config, apigwClient apigw.APIGatewayClient, renderedSwag []byte) (*apigateway.RestApi, error) {
+	switch {
+	case cfg.APIGateway.Owner != "":
+		return apigwClient.ImportOwnedSwagger(renderedSwag, cfg.APIGateway.ID, cfg.APIGateway.Owner)
+	case cfg.IsMerge():
+		return apigwClient.MergeSwagger(renderedSwag, cfg.APIGateway.ID)
+	default:
+		return apigwClient.ImportSwagger(renderedSwag, cfg.APIGateway.ID)
+	}
+}
+
+func deploy(cfg config.Config, apigwClient apigw.APIGatewayClient) error {
+	var deployment *apigateway.Deployment
+	var err error
+	if cfg.Canary.Enabled() {
+		deployment, err = apigwClient.CreateCanaryDeployment(cfg.APIGateway.Stage, cfg.APIGateway.ID, cfg.DeploymentVariables(), cfg.Canary)
+	} else {
+		deployment, err = apigwClient.CreateDeployment(cfg.APIGateway.Stage, cfg.APIGateway.ID, cfg.DeploymentVariables())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to deploy the newly created resources: %w", err)
+	}
+	log.Info(deployment)
+
+	if err := apigwClient.UpdateStage(cfg.APIGateway.ID, cfg.APIGateway.Stage, cfg.StageSettings); err != nil {
+		return fmt.Errorf("failed to update the stage settings: %w", err)
+	}
+	// the plans are associated with the stage, it exists once deployed
+	if err := apigwClient.ProvisionUsagePlans(cfg.APIGateway.ID, cfg.APIGateway.Stage, cfg.UsagePlans); err != nil {
+		return fmt.Errorf("failed to provision the usage plans: %w", err)
+	}
+	return nil
+}
+
+// diffDeployedSwagger compares the rendered document with the one exported from the deployed stage and writes the
+// differences in the configured format
+func diffDeployedSwagger(cfg config.Config, apigwClient apigw.APIGatewayClient, renderedSwag []byte) (diff.Report, error) {
+	var changes diff.Report
+	deployed, err := apigwClient.ExportSwagger(cfg.APIGateway.Stage, cfg.APIGateway.ID)
+	if err != nil {
+		return changes, err
+	}
+	var rendered swg.Swagger
+	if err := json.Unmarshal(renderedSwag, &rendered); err != nil {
+		return changes, err
+	}
+
+	changes = diff.Compare(deployed, rendered)
+	if cfg.IsMerge() {
+		// only the stale routes of the owner are removed on merge
+		var removed []string
+		if cfg.APIGateway.Owner != "" {
+			owned, err := apigwClient.OwnedRoutes(cfg.APIGateway.ID)
+			if err != nil {
+				return changes, err
+			}
+			for _, route := range apigw.StaleRoutes(rendered, owned, cfg.APIGateway.Owner) {
+				removed = append(removed, route.String())
+			}
+		}
+		changes = changes.Merged(removed)
+	}
+	if cfg.Diff.Format == config.JsonFormat {
+		data, err := changes.JSON()
+		if err != nil {
+			return changes, err
+		}
+		_, err = fmt.Fprintln(reports, string(data))
+		return changes, err
+	}
+	_, err = fmt.Fprint(reports, changes)
+	return changes, err
+}
+
+func checkRemovedRoutes(cfg config.Config, changes diff.Report) error {
+	if removed := changes.RemovedRoutes(); cfg.Diff.FailOnRouteRemoval && len(removed) > 0 {
+		return fmt.Errorf("the import would remove %d deployed route(s): %v", len(removed), removed)
+	}
+	return nil
+}
+
+// writeRendered writes the rendered document to the given file, or stdout when set to `-`, followed by the render summary.
+func writeRendered(renderedSwag []byte, report swagger.RenderReport, output string) error {
+	var doc bytes.Buffer
+	if err := json.Indent(&doc, renderedSwag, "", "  "); err != nil {
+		return err
+	}
+	doc.WriteString("\n")
+
+	if output == config.StdOutput {
+		if _, err := doc.WriteTo(os.Stdout); err != nil {
+			return err
+		}
+	} else {
+		if err := ioutil.WriteFile(output, doc.Bytes(), 0644); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"file": output}).Info("Rendered swagger document written")
+	}
+	_, err := fmt.Fprint(reports, report)
+	return err
+}
@@ -0,0 +1,503 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akhettar/apigw-pub/apigw"
+	"github.com/akhettar/apigw-pub/swagger"
+)
+
+const (
+	StageNameVarKey = "STAGE_NAME"
+	APIGatewayIDKey = "API_GATEWAY_ID"
+	SwaggerUrl      = "SWAGGER_URL"
+	DryRun          = "DRY_RUN"
+	DryRunOutput    = "DRY_RUN_OUTPUT"
+	DiffEnabled     = "DIFF_ENABLED"
+	DiffFormat      = "DIFF_FORMAT"
+	FailOnRemoval   = "FAIL_ON_ROUTE_REMOVAL"
+	ImportMode      = "IMPORT_MODE"
+	Owner           = "API_OWNER"
+
+	StdOutput  = "-"
+	TextFormat = "text"
+	JsonFormat = "json"
+)
+
+var stageVariableName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+var stageVariableValue = regexp.MustCompile(`^[A-Za-z0-9\-._~:/?#&=,]+$`)
+
+// Group a set of settings a command depends on
+type Group int
+
+const (
+	// SourceGroup the swagger url and the render options
+	SourceGroup Group = iota
+	// GatewayGroup the api gateway id and the aws settings
+	GatewayGroup
+	// StageGroup the stage the api is deployed to
+	StageGroup
+	// OutputGroup where the rendered swagger is written to
+	OutputGroup
+	// DiffGroup the diff against the deployed api
+	DiffGroup
+)
+
+// Config the publisher settings, it is also the schema of the configuration file
+type Config struct {
+	SwaggerURL     string         `yaml:"swaggerUrl"`
+	EndpointURL    string         `yaml:"endpointUrl"`
+	EndpointScheme string         `yaml:"endpointScheme"`
+	EndpointPort   string         `yaml:"endpointPort"`
+	Region         string         `yaml:"region"`
+	AssumeRole     string         `yaml:"assumeRole"`
+	AccountID      string         `yaml:"accountId"`
+	APIGateway     APIGateway     `yaml:"apiGateway"`
+	ConnectionType string         `yaml:"connectionType"`
+	VPCLinkID      string         `yaml:"vpcLinkId"`
+	Auth           Auth           `yaml:"auth"`
+	Cors           Cors           `yaml:"cors"`
+	Proxy          Proxy          `yaml:"proxy"`
+	StageVariables StageVariables `yaml:"stageVariables"`
+	// RequestValidator the validator of the operations: none, body, params or full
+	RequestValidator string    `yaml:"requestValidator"`
+	Responses        Responses `yaml:"responses"`
+	// GatewayResponses the responses API Gateway returns when it rejects a request, keyed by type, exp: UNAUTHORIZED
+	GatewayResponses map[string]swagger.GatewayResponse `yaml:"gatewayResponses"`
+	// ResourcePolicy the callers allowed to invoke the API
+	ResourcePolicy swagger.ResourcePolicy `yaml:"resourcePolicy"`
+	// StageSettings the throttling, caching, logging and tracing applied to the stage after the deployment
+	StageSettings apigw.StageSettings `yaml:"stageSettings"`
+	// Canary the canary deployment of the stage, promoted or rolled back with the promote and rollback commands
+	Canary apigw.Canary `yaml:"canary"`
+	// UsagePlans the usage plans and api keys provisioned after the deployment
+	UsagePlans    apigw.UsagePlans `yaml:"usagePlans"`
+	CustomHeaders []string         `yaml:"customHeaders"`
+	Output        string           `yaml:"output"`
+	DryRun        bool             `yaml:"dryRun"`
+	Diff          Diff             `yaml:"diff"`
+	Fetch         Fetch            `yaml:"fetch"`
+	Services      []Service        `yaml:"services"`
+	// Integrations the backends of the routes (`POST /orders`) or paths (`/orders`) not using the http integration
+	Integrations map[string]swagger.Integration `yaml:"integrations"`
+
+	// problems found in the environment variables, reported by Validate with the source and stage settings
+	envProblems   []string
+	stageProblems []string
+}
+
+// APIGateway the REST API the swagger is published to
+type APIGateway struct {
+	ID    string `yaml:"id"`
+	Name  string `yaml:"name"`
+	Stage string `yaml:"stage"`
+	// ImportMode overwrite replaces the whole REST API, merge adds and updates the imported routes only
+	ImportMode string `yaml:"importMode"`
+	// Owner the service owning the imported routes, its routes no longer published are deleted on merge
+	Owner string `yaml:"owner"`
+}
+
+// Auth the authorizer securing the endpoints
+type Auth struct {
+	Type string `yaml:"type"`
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// ProviderARNs the user pools of the cognito authorizer
+	ProviderARNs []string `yaml:"providerArns"`
+	// the lambda authorizer of the apiKey auth
+	swagger.LambdaAuthorizer `yaml:",inline"`
+	// Authorizers the authorizers the operations select with their security requirements or x-authorizer extension
+	Authorizers []swagger.Authorizer `yaml:"authorizers"`
+}
+
+// Cors the cors support added to the endpoints, the `x-apigw-pub-cors` extension of a path overrides the settings
+type Cors struct {
+	Enabled      bool `yaml:"enabled"`
+	swagger.Cors `yaml:",inline"`
+}
+
+// Proxy the greedy `ANY /{proxy+}` route passing the requests not matching any path through to the backend
+type Proxy struct {
+	Enabled bool `yaml:"enabled"`
+	// Authorizer the name of the authorizer guarding the route, the default authorizer when empty
+	Authorizer     string `yaml:"authorizer"`
+	APIKeyRequired bool   `yaml:"apiKeyRequired"`
+}
+
+// StageVariables the integrations reference the endpointUrl and vpcLinkId stage variables, set on the stage when deploying,
+// rather than their values so the same document can be promoted from one stage to the next
+type StageVariables struct {
+	Enabled bool `yaml:"enabled"`
+	// Variables the other stage variables set on the stage, exp: the ones used in the endpoint url
+	Variables map[string]string `yaml:"variables"`
+}
+
+// Responses the integration responses of every operation, along with the ones generated from the declared responses
+type Responses struct {
+	// Headers passed through from the backend on every response, exp: Location
+	Headers []string `yaml:"headers"`
+	// Mappings replace the default responses: 200, 201, 202, 204, 400, 401, 403, 404, 409, 424 and 500
+	Mappings []swagger.ResponseMapping `yaml:"mappings"`
+}
+
+// Diff the comparison with the deployed api run before the import
+type Diff struct {
+	Enabled            bool   `yaml:"enabled"`
+	Format             string `yaml:"format"`
+	FailOnRouteRemoval bool   `yaml:"failOnRouteRemoval"`
+}
+
+// Fetch the settings used to fetch the swagger document from a remote service
+type Fetch struct {
+	Timeout      time.Duration     `yaml:"timeout"`
+	Retries      int               `yaml:"retries"`
+	RetryBackoff time.Duration     `yaml:"retryBackoff"`
+	BearerToken  string            `yaml:"bearerToken"`
+	BasicAuth    BasicAuth         `yaml:"basicAuth"`
+	Headers      map[string]string `yaml:"headers"`
+	CABundle     string            `yaml:"caBundle"`
+	ClientCert   string            `yaml:"clientCert"`
+	ClientKey    string            `yaml:"clientKey"`
+}
+
+// BasicAuth the credentials of a swagger endpoint protected with basic auth
+type BasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Service one of the services merged into the REST API, each service is integrated with its own endpoint
+type Service struct {
+	Name           string `yaml:"name"`
+	SwaggerURL     string `yaml:"swaggerUrl"`
+	EndpointURL    string `yaml:"endpointUrl"`
+	BasePath       string `yaml:"basePath"`
+	ConnectionType string `yaml:"connectionType"`
+	VPCLinkID      string `yaml:"vpcLinkId"`
+}
+
+// Default - Function
+// Returns the settings used when nothing is configured
+func Default() Config {
+	return Config{
+		Output:     StdOutput,
+		APIGateway: APIGateway{ImportMode: apigw.OverwriteMode},
+		Diff:       Diff{Format: TextFormat},
+		Fetch:      Fetch{Timeout: swagger.DefaultTimeout, Retries: swagger.DefaultRetries, RetryBackoff: swagger.DefaultRetryBackoff},
+	}
+}
+
+// FromEnv - Function
+// Reads all the settings from the environment variables
+func FromEnv() Config {
+	cfg := Default()
+	cfg.ApplyEnv()
+	return cfg
+}
+
+// ApplyEnv overrides the settings with the environment variables which are set
+func (c *Config) ApplyEnv() {
+	lookup(SwaggerUrl, &c.SwaggerURL)
+	lookup(swagger.EndpointUrl, &c.EndpointURL)
+	lookup(swagger.EndpointScheme, &c.EndpointScheme)
+	lookup(swagger.EndpointPort, &c.EndpointPort)
+	lookup(apigw.Region, &c.Region)
+	lookup(apigw.AssumeRole, &c.AssumeRole)
+	lookup(apigw.APIKeysFile, &c.UsagePlans.KeysFile)
+	lookup(apigw.HealthCheckURL, &c.Canary.HealthCheck.URL)
+	if value, ok := os.LookupEnv(apigw.CanaryPercent); ok {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.stageProblems = append(c.stageProblems, fmt.Sprintf("invalid canary percent (%s) %q, expected a number", apigw.CanaryPercent, value))
+		}
+		c.Canary.Percent = percent
+	}
+	lookup(swagger.AWSAccountID, &c.AccountID)
+	lookup(APIGatewayIDKey, &c.APIGateway.ID)
+	lookup(swagger.ApiGwName, &c.APIGateway.Name)
+	lookup(StageNameVarKey, &c.APIGateway.Stage)
+	lookup(ImportMode, &c.APIGateway.ImportMode)
+	lookup(Owner, &c.APIGateway.Owner)
+	lookup(swagger.ConnectionType, &c.ConnectionType)
+	lookup(swagger.VPCLinkID, &c.VPCLinkID)
+	lookup(swagger.AuthType, &c.Auth.Type)
+	lookup(swagger.AuthName, &c.Auth.Name)
+	lookup(swagger.AuthUrl, &c.Auth.URL)
+	lookup(swagger.ProxyAuthorizer, &c.Proxy.Authorizer)
+	lookup(swagger.RequestValidator, &c.RequestValidator)
+	lookup(DryRunOutput, &c.Output)
+	lookup(DiffFormat, &c.Diff.Format)
+
+	if arns, ok := os.LookupEnv(swagger.AuthProviderARNs); ok {
+		c.Auth.ProviderARNs = swagger.SplitList(arns)
+	}
+	if headers, ok := os.LookupEnv(swagger.CustomHeaders); ok {
+		c.CustomHeaders = swagger.SplitList(headers)
+	}
+	if headers, ok := os.LookupEnv(swagger.ResponseHeaders); ok {
+		c.Responses.Headers = swagger.SplitList(headers)
+	}
+
+	// cors is enabled by the presence of the environment variable, the other flags are parsed as booleans
+	if _, ok := os.LookupEnv(swagger.CorsEnabled); ok {
+		c.Cors.Enabled = true
+	}
+	problems := swagger.LookupBool(swagger.ProxyEnabled, &c.Proxy.Enabled)
+	problems = append(problems, swagger.LookupBool(swagger.ProxyAPIKeyRequired, &c.Proxy.APIKeyRequired)...)
+	problems = append(problems, swagger.LookupBool(swagger.StageVariablesEnabled, &c.StageVariables.Enabled)...)
+	problems = append(problems, swagger.LookupBool(DryRun, &c.DryRun)...)
+	problems = append(problems, swagger.LookupBool(DiffEnabled, &c.Diff.Enabled)...)
+	problems = append(problems, swagger.LookupBool(FailOnRemoval, &c.Diff.FailOnRouteRemoval)...)
+
+	fetch := c.Fetch.options()
+	c.envProblems = append(problems, fetch.ApplyEnv()...)
+	c.envProblems = append(c.envProblems, c.Cors.ApplyEnv()...)
+	c.envProblems = append(c.envProblems, c.Auth.LambdaAuthorizer.ApplyEnv()...)
+	c.Fetch = Fetch{
+		Timeout:      fetch.Timeout,
+		Retries:      fetch.Retries,
+		RetryBackoff: fetch.RetryBackoff,
+		BearerToken:  fetch.BearerToken,
+		BasicAuth:    BasicAuth{Username: fetch.Username, Password: fetch.Password},
+		Headers:      fetch.Headers,
+		CABundle:     fetch.CABundle,
+		ClientCert:   fetch.ClientCert,
+		ClientKey:    fetch.ClientKey,
+	}
+}
+
+// RenderOptions returns the options used to render the swagger document
+func (c Config) RenderOptions() swagger.Options {
+	return swagger.Options{
+		APIGatewayName:        c.APIGateway.Name,
+		EndpointURL:           c.EndpointURL,
+		EndpointScheme:        c.EndpointScheme,
+		EndpointPort:          c.EndpointPort,
+		ConnectionType:        c.ConnectionType,
+		VPCLinkID:             c.VPCLinkID,
+		AuthType:              c.Auth.Type,
+		AuthName:              c.Auth.Name,
+		AuthURL:               c.Auth.URL,
+		AuthProviderARNs:      c.Auth.ProviderARNs,
+		Authorizer:            c.Auth.LambdaAuthorizer,
+		Authorizers:           c.Auth.Authorizers,
+		CorsEnabled:           c.Cors.Enabled,
+		Cors:                  c.Cors.Cors,
+		ProxyEnabled:          c.Proxy.Enabled,
+		ProxyAuthorizer:       c.Proxy.Authorizer,
+		ProxyAPIKeyRequired:   c.Proxy.APIKeyRequired,
+		StageVariablesEnabled: c.StageVariables.Enabled,
+		RequestValidator:      c.RequestValidator,
+		ResponseMappings:      c.Responses.Mappings,
+		ResponseHeaders:       c.Responses.Headers,
+		GatewayResponses:      c.GatewayResponses,
+		Policy:                c.ResourcePolicy,
+		Region:                c.Region,
+		AccountID:             c.AccountID,
+		Integrations:          c.Integrations,
+		CustomHeaders:         c.CustomHeaders,
+		Fetch:                 c.Fetch.options(),
+	}
+}
+
+// RenderServices returns the services merged into the REST API, the service settings override the render options
+func (c Config) RenderServices() []swagger.Service {
+	var services []swagger.Service
+	for _, service := range c.Services {
+		options := c.RenderOptions()
+		options.EndpointURL = service.EndpointURL
+		options.StageVariablePrefix = service.Name
+		if service.ConnectionType != "" {
+			options.ConnectionType = service.ConnectionType
+			options.VPCLinkID = service.VPCLinkID
+		}
+		services = append(services, swagger.Service{
+			Name:       service.Name,
+			SwaggerURL: service.SwaggerURL,
+			BasePath:   service.BasePath,
+			Options:    options,
+		})
+	}
+	return services
+}
+
+// DeploymentVariables returns the stage variables set on the stage when deploying: the ones referenced by the integrations
+// of every service and the configured ones
+func (c Config) DeploymentVariables() map[string]string {
+	options := []swagger.Options{c.RenderOptions()}
+	if len(c.Services) > 0 {
+		options = nil
+		for _, service := range c.RenderServices() {
+			options = append(options, service.Options)
+		}
+	}
+
+	variables := map[string]string{}
+	for _, o := range options {
+		for name, value := range o.StageVariables() {
+			variables[name] = value
+		}
+	}
+	for name, value := range c.StageVariables.Variables {
+		variables[name] = value
+	}
+	return variables
+}
+
+// validateStageVariables checks the referenced stage variables can be set and the configured ones are valid
+func (c Config) validateStageVariables() []string {
+	var problems []string
+	if c.StageVariables.Enabled {
+		if len(c.Services) == 0 && c.EndpointURL == "" {
+			problems = append(problems, fmt.Sprintf("the endpoint url (--endpoint-url or %s) is required to set the %s stage variable", swagger.EndpointUrl, swagger.EndpointURLVariable))
+		}
+		for _, service := range c.Services {
+			if service.EndpointURL == "" {
+				problems = append(problems, fmt.Sprintf("service %s: the endpointUrl is required to set its stage variable", service.Name))
+			}
+		}
+	}
+
+	var names []string
+	for name := range c.StageVariables.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !stageVariableName.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("invalid stage variable name %q, only alphanumeric characters and underscores are allowed", name))
+		}
+		if !stageVariableValue.MatchString(c.StageVariables.Variables[name]) {
+			problems = append(problems, fmt.Sprintf("invalid value of the stage variable %s, only alphanumeric characters and -._~:/?#&=, are allowed", name))
+		}
+	}
+	return problems
+}
+
+func (f Fetch) options() swagger.FetchOptions {
+	return swagger.FetchOptions{
+		Timeout:      f.Timeout,
+		Retries:      f.Retries,
+		RetryBackoff: f.RetryBackoff,
+		BearerToken:  f.BearerToken,
+		Username:     f.BasicAuth.Username,
+		Password:     f.BasicAuth.Password,
+		Headers:      f.Headers,
+		CABundle:     f.CABundle,
+		ClientCert:   f.ClientCert,
+		ClientKey:    f.ClientKey,
+	}
+}
+
+// IsMerge true when the routes are merged into the REST API rather than replacing it
+func (c Config) IsMerge() bool {
+	return c.APIGateway.ImportMode == apigw.MergeMode
+}
+
+// ValidationError lists all the problems found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n  - %s", strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks the settings of the given groups, all the problems are reported at once
+func (c Config) Validate(groups ...Group) error {
+	var problems []string
+	for _, group := range groups {
+		switch group {
+		case SourceGroup:
+			if len(c.Services) == 0 {
+				problems = append(problems, required(c.SwaggerURL, "swagger url", "swagger-url", SwaggerUrl)...)
+			}
+			problems = append(problems, c.envProblems...)
+			problems = append(problems, c.RenderOptions().Validate()...)
+			problems = append(problems, c.validateServices()...)
+		case GatewayGroup:
+			problems = append(problems, required(c.APIGateway.ID, "api gateway id", "api-gateway-id", APIGatewayIDKey)...)
+			switch c.APIGateway.ImportMode {
+			case "", apigw.OverwriteMode:
+				if c.APIGateway.Owner != "" {
+					problems = append(problems, fmt.Sprintf("the owner (--owner or %s) requires the %s import mode", Owner, apigw.MergeMode))
+				}
+			case apigw.MergeMode:
+			default:
+				problems = append(problems, fmt.Sprintf("unsupported import mode (--import-mode or %s) %q, expected one of %s, %s",
+					ImportMode, c.APIGateway.ImportMode, apigw.OverwriteMode, apigw.MergeMode))
+			}
+		case StageGroup:
+			problems = append(problems, required(c.APIGateway.Stage, "stage name", "stage", StageNameVarKey)...)
+			problems = append(problems, c.validateStageVariables()...)
+			problems = append(problems, c.stageProblems...)
+			problems = append(problems, c.StageSettings.Validate()...)
+			problems = append(problems, c.Canary.Validate()...)
+			problems = append(problems, c.UsagePlans.Validate()...)
+		case OutputGroup:
+			problems = append(problems, required(c.Output, "output", "output", DryRunOutput)...)
+		case DiffGroup:
+			if c.Diff.Format != TextFormat && c.Diff.Format != JsonFormat {
+				problems = append(problems, fmt.Sprintf("unsupported diff format (--diff-format or %s) %q, expected one of %s, %s",
+					DiffFormat, c.Diff.Format, TextFormat, JsonFormat))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateServices checks the services merged into the REST API, the problems of the shared options are only reported once
+func (c Config) validateServices() []string {
+	if len(c.Services) == 0 {
+		return nil
+	}
+	var problems []string
+	if c.SwaggerURL != "" {
+		problems = append(problems, fmt.Sprintf("the swagger url (--swagger-url or %s) and the services are mutually exclusive", SwaggerUrl))
+	}
+
+	shared := map[string]bool{}
+	for _, problem := range c.RenderOptions().Validate() {
+		shared[problem] = true
+	}
+	names := map[string]bool{}
+	for i, service := range c.RenderServices() {
+		if service.Name == "" {
+			problems = append(problems, fmt.Sprintf("the name of the service %d is required", i+1))
+		} else if names[service.Name] {
+			problems = append(problems, fmt.Sprintf("the service %s is defined more than once", service.Name))
+		}
+		names[service.Name] = true
+		if service.SwaggerURL == "" {
+			problems = append(problems, fmt.Sprintf("the swagger url of the service %s is required", service.Name))
+		}
+		for _, problem := range service.Options.Validate() {
+			if !shared[problem] {
+				problems = append(problems, fmt.Sprintf("service %s: %s", service.Name, problem))
+			}
+		}
+	}
+	return problems
+}
+
+func required(value, name, flagName, envKey string) []string {
+	if value != "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("the %s (--%s or %s) is required", name, flagName, envKey)}
+}
+
+func lookup(key string, value *string) {
+	if envValue, ok := os.LookupEnv(key); ok {
+		*value = envValue
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/akhettar/apigw-pub/swagger"
+)
+
+const (
+	// CheckMark used for unit test highlight.
+	CheckMark = "\u2713"
+
+	// BallotX used for unit test highlight.
+	BallotX = "\u2717"
+)
+
+func TestValidate_ShouldReportAllTheProblemsAtOnce(t *testing.T) {
+
+	t.Logf("Given an empty configuration")
+	{
+		t.Logf("\tWhen validating the publish settings, all the missing settings should be reported")
+		{
+			cfg := Config{ConnectionType: "VPC_LINK", Auth: Auth{Type: "apiKey"}, Output: StdOutput, Diff: Diff{Format: "yaml"}}
+			err := cfg.Validate(SourceGroup, GatewayGroup, StageGroup, OutputGroup, DiffGroup)
+
+			validationErr, ok := err.(ValidationError)
+			if !ok {
+				t.Fatalf("\t\tA validation error should be returned, got %v %v", err, BallotX)
+			}
+
+			expected := []string{"SWAGGER_URL", "API_GATEWAY_NAME", "VPC_LINK_ID", "AUTH_NAME", "AUTH_URL", "API_GATEWAY_ID", "STAGE_NAME", "DIFF_FORMAT"}
+			if len(validationErr.Problems) == len(expected) {
+				t.Logf("\t\tAll the problems should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAll the problems should be reported, got %v %v", validationErr.Problems, BallotX)
+			}
+			for _, key := range expected {
+				if !strings.Contains(validationErr.Error(), key) {
+					t.Errorf("\t\tThe problem with %s should be reported %v", key, BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestBind_FlagsShouldTakePrecedenceOverEnvironmentVariables(t *testing.T) {
+
+	t.Logf("Given the stage and api gateway id are set in the environment variables")
+	{
+		os.Setenv(StageNameVarKey, "env-stage")
+		os.Setenv(APIGatewayIDKey, "env-id")
+		defer os.Unsetenv(StageNameVarKey)
+		defer os.Unsetenv(APIGatewayIDKey)
+
+		t.Logf("\tWhen only the stage flag is passed")
+		{
+			cfg := FromEnv()
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			cfg.Bind(fs, GatewayGroup, StageGroup)
+			fs.Parse([]string{"--stage", "flag-stage"})
+
+			if cfg.APIGateway.Stage == "flag-stage" {
+				t.Logf("\t\tThe flag value should be used %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag value should be used, got %s %v", cfg.APIGateway.Stage, BallotX)
+			}
+
+			if cfg.APIGateway.ID == "env-id" {
+				t.Logf("\t\tThe environment variable should be used as fallback %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe environment variable should be used as fallback, got %s %v", cfg.APIGateway.ID, BallotX)
+			}
+		}
+	}
+}
+
+func TestApplyEnv_ShouldReportInvalidFetchSettings(t *testing.T) {
+
+	t.Logf("Given the swagger fetch settings are set in the environment variables")
+	{
+		os.Setenv(swagger.SwaggerTimeout, "10 seconds")
+		os.Setenv(swagger.SwaggerRetries, "5")
+		os.Setenv(swagger.SwaggerHeaders, "X-Api-Key: key")
+		defer os.Unsetenv(swagger.SwaggerTimeout)
+		defer os.Unsetenv(swagger.SwaggerRetries)
+		defer os.Unsetenv(swagger.SwaggerHeaders)
+
+		t.Logf("\tWhen reading the configuration, the valid settings should be applied and the invalid ones reported")
+		{
+			cfg := FromEnv()
+			if cfg.Fetch.Retries == 5 && cfg.Fetch.Headers["X-Api-Key"] == "key" {
+				t.Logf("\t\tThe retries and headers should be applied %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe retries and headers should be applied, got %+v %v", cfg.Fetch, BallotX)
+			}
+
+			err := cfg.Validate(SourceGroup)
+			if err != nil && strings.Contains(err.Error(), swagger.SwaggerTimeout) {
+				t.Logf("\t\tThe invalid timeout should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe invalid timeout should be reported, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
+
+func TestApplyEnv_ShouldParseTheBooleanSettings(t *testing.T) {
+
+	t.Logf("Given the boolean settings are set to false in the environment variables")
+	{
+		for _, key := range []string{DryRun, swagger.ProxyEnabled, swagger.StageVariablesEnabled, DiffEnabled, FailOnRemoval} {
+			os.Setenv(key, "false")
+			defer os.Unsetenv(key)
+		}
+		os.Setenv(swagger.CorsEnabled, "")
+		defer os.Unsetenv(swagger.CorsEnabled)
+
+		t.Logf("\tWhen reading the configuration, the settings should be disabled")
+		{
+			cfg := FromEnv()
+			if !cfg.DryRun && !cfg.Proxy.Enabled && !cfg.StageVariables.Enabled && !cfg.Diff.Enabled && !cfg.Diff.FailOnRouteRemoval {
+				t.Logf("\t\tThe settings should be disabled %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe settings should be disabled, got %+v %v", cfg, BallotX)
+			}
+			if cfg.Cors.Enabled {
+				t.Logf("\t\tThe cors should still be enabled by the presence of %s %v", swagger.CorsEnabled, CheckMark)
+			} else {
+				t.Errorf("\t\tThe cors should still be enabled by the presence of %s %v", swagger.CorsEnabled, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen a setting is neither true nor false, it should be reported")
+		{
+			os.Setenv(DryRun, "yes")
+			err := FromEnv().Validate(SourceGroup)
+			if err != nil && strings.Contains(err.Error(), DryRun) {
+				t.Logf("\t\tThe invalid dry run should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe invalid dry run should be reported, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
+
+func TestValidate_ShouldReportServiceProblems(t *testing.T) {
+
+	t.Logf("Given several services are merged into the REST API")
+	{
+		cfg := Default()
+		cfg.APIGateway.Name = "api-gw-dev"
+		cfg.Services = []Service{
+			{Name: "account", SwaggerURL: "../data/swagger.json", ConnectionType: "VPC_LINK"},
+			{Name: "account"},
+		}
+
+		t.Logf("\tWhen validating the source settings, the problems of each service should be reported")
+		{
+			err := cfg.Validate(SourceGroup)
+			expected := []string{
+				"service account: the vpc link id (VPC_LINK_ID) is required for the VPC_LINK connection type",
+				"the service account is defined more than once",
+				"the swagger url of the service account is required",
+			}
+			for _, problem := range expected {
+				if err != nil && strings.Contains(err.Error(), problem) {
+					t.Logf("\t\t%q should be reported %v", problem, CheckMark)
+				} else {
+					t.Errorf("\t\t%q should be reported, got %v %v", problem, err, BallotX)
+				}
+			}
+			if strings.Contains(err.Error(), SwaggerUrl) {
+				t.Errorf("\t\tThe swagger url should not be required %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestValidate_ShouldRequireTheMergeModeForAnOwner(t *testing.T) {
+
+	t.Logf("Given an owner is set with the default overwrite import mode")
+	{
+		cfg := Default()
+		cfg.APIGateway.ID = "nizzzddqg"
+		cfg.APIGateway.Owner = "account"
+
+		t.Logf("\tWhen validating the gateway settings")
+		{
+			err := cfg.Validate(GatewayGroup)
+			if err != nil && strings.Contains(err.Error(), "the owner (--owner or API_OWNER) requires the merge import mode") {
+				t.Logf("\t\tThe owner should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe owner should be reported, got %v %v", err, BallotX)
+			}
+
+			cfg.APIGateway.ImportMode = "merge"
+			if err := cfg.Validate(GatewayGroup); err == nil {
+				t.Logf("\t\tThe owner should be accepted in merge mode %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe owner should be accepted in merge mode, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
+
+func TestDeploymentVariables_ShouldSetTheReferencedStageVariables(t *testing.T) {
+
+	t.Logf("Given the stage variables are enabled for two services, one behind a vpc link")
+	{
+		cfg := Default()
+		cfg.StageVariables.Enabled = true
+		cfg.StageVariables.Variables = map[string]string{"logLevel": "debug"}
+		cfg.Services = []Service{
+			{Name: "account-service", SwaggerURL: "account.json", EndpointURL: "https://account.internal:8443/api"},
+			{Name: "orders", SwaggerURL: "orders.json", EndpointURL: "orders.internal", ConnectionType: swagger.VPCLinkConnectionType, VPCLinkID: "abc123"},
+		}
+
+		t.Logf("\tWhen deploying, the variables of every service should be set on the stage")
+		{
+			expected := map[string]string{
+				"accountServiceEndpointUrl": "account.internal:8443/api",
+				"ordersEndpointUrl":         "orders.internal",
+				"ordersVpcLinkId":           "abc123",
+				"logLevel":                  "debug",
+			}
+			variables := cfg.DeploymentVariables()
+			if reflect.DeepEqual(variables, expected) {
+				t.Logf("\t\tThe stage variables should be set %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe stage variables should be set, got %v %v", variables, BallotX)
+			}
+		}
+	}
+}
+
+func TestBind_ShouldBindTheStageVariablesFlagsOfTheDeploy(t *testing.T) {
+
+	t.Logf("Given the stage variables and the endpoint url are passed as flags")
+	{
+		args := []string{"--stage", "dev", "--stage-variables", "--endpoint-url", "https://account.internal/api"}
+
+		t.Logf("\tWhen binding the stage flags only, as the deploy command does, the endpoint url stage variable should be set")
+		{
+			cfg := Default()
+			fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+			cfg.Bind(fs, GatewayGroup, StageGroup)
+			if err := fs.Parse(args); err != nil {
+				t.Fatalf("\t\tFailed to parse the flags %v %v", err, BallotX)
+			}
+			if variables := cfg.DeploymentVariables(); variables[swagger.EndpointURLVariable] == "account.internal/api" {
+				t.Logf("\t\tThe endpoint url stage variable should be set %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe endpoint url stage variable should be set, got %v %v", variables, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen binding the source and stage flags, as the publish command does, the flags should be registered once")
+		{
+			cfg := Default()
+			fs := flag.NewFlagSet("publish", flag.ContinueOnError)
+			cfg.Bind(fs, SourceGroup, GatewayGroup, StageGroup)
+			if err := fs.Parse(args); err == nil && cfg.StageVariables.Enabled {
+				t.Logf("\t\tThe flags should be parsed %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should be parsed, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
+
+func TestValidate_ShouldReportInvalidStageVariables(t *testing.T) {
+
+	t.Logf("Given the stage variables are enabled without an endpoint url and an invalid variable")
+	{
+		cfg := Default()
+		cfg.APIGateway.Stage = "dev"
+		cfg.StageVariables.Enabled = true
+		cfg.StageVariables.Variables = map[string]string{"backend-host": "dev internal"}
+
+		t.Logf("\tWhen validating the stage settings")
+		{
+			err := cfg.Validate(StageGroup)
+			for _, problem := range []string{
+				"the endpoint url (--endpoint-url or ENDPOINT_URL) is required",
+				`invalid stage variable name "backend-host"`,
+				"invalid value of the stage variable backend-host",
+			} {
+				if err != nil && strings.Contains(err.Error(), problem) {
+					t.Logf("\t\t%q should be reported %v", problem, CheckMark)
+				} else {
+					t.Errorf("\t\t%q should be reported, got %v %v", problem, err, BallotX)
+				}
+			}
+		}
+	}
+}
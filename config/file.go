@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ConfigFile      = "APIGW_PUB_CONFIG"
+	Environment     = "APIGW_PUB_ENVIRONMENT"
+	environmentsKey = "environments"
+)
+
+// DefaultFiles the configuration files looked up in the working directory when none is given
+var DefaultFiles = []string{"apigw-pub.yaml", "apigw-pub.yml", "apigw-pub.json"}
+
+var lineRegexp = regexp.MustCompile(`^line \d+: `)
+
+// ${VAR} or ${VAR:-default}
+var interpolationRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Load - Function
+// Loads the settings from the configuration file, with the overlay of the given environment (dev, stage, prod...) applied,
+// then from the environment variables. The file is strictly validated against the Config schema: unknown or mistyped keys
+// are reported along with every other problem found.
+func Load(path, environment string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = defaultFile()
+	}
+	if path == "" {
+		if environment != "" {
+			return cfg, ValidationError{Problems: []string{fmt.Sprintf("the environment %q requires a configuration file (--config or %s)", environment, ConfigFile)}}
+		}
+		cfg.ApplyEnv()
+		return cfg, nil
+	}
+
+	log.WithFields(log.Fields{"file": path, "environment": environment}).Info("Loading configuration file")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if problems := decode(data, environment, &cfg); len(problems) > 0 {
+		return cfg, ValidationError{Problems: problems}
+	}
+	cfg.ApplyEnv()
+	return cfg, nil
+}
+
+// Locate returns the configuration file and the environment given on the command line, with a fallback to the
+// environment variables. The flags are looked up ahead of parsing as they drive the defaults of every other flag.
+func Locate(args []string) (string, string) {
+	path, environment := os.Getenv(ConfigFile), os.Getenv(Environment)
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			break
+		}
+		// values of the other flags are skipped
+		name := strings.TrimLeft(args[i], "-")
+		if name == args[i] {
+			continue
+		}
+		value := ""
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			name, value = parts[0], parts[1]
+		} else if i+1 < len(args) {
+			value = args[i+1]
+		}
+
+		switch name {
+		case "config":
+			path = value
+		case "environment":
+			environment = value
+		}
+	}
+	return path, environment
+}
+
+func defaultFile() string {
+	for _, file := range DefaultFiles {
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// decode merges the environment overlay into the file settings, interpolates the environment variables and decodes the
+// result into the given config
+func decode(data []byte, environment string, cfg *Config) []string {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	environments, _ := doc[environmentsKey].(map[interface{}]interface{})
+	delete(doc, environmentsKey)
+
+	// every overlay is validated, not only the selected one, so typos are caught before they reach another environment
+	var names []string
+	for name, overlay := range environments {
+		names = append(names, fmt.Sprint(name))
+		for _, problem := range strictDecode(overlay, &Config{}) {
+			problems = append(problems, fmt.Sprintf("%s.%v: %s", environmentsKey, name, problem))
+		}
+	}
+	sort.Strings(names)
+
+	if environment != "" {
+		overlay, ok := environments[environment]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("environment %q not found in the configuration file, expected one of %v", environment, names))
+		} else if overlay, ok := overlay.(map[interface{}]interface{}); ok {
+			merge(doc, overlay)
+		}
+	}
+
+	merged, interpolationProblems := interpolate(doc)
+	problems = append(problems, interpolationProblems...)
+	problems = append(problems, strictDecode(merged, cfg)...)
+	return problems
+}
+
+func strictDecode(value interface{}, cfg *Config) []string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	err = yaml.UnmarshalStrict(data, cfg)
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		// the line numbers refer to the merged document, not to the configuration file
+		var problems []string
+		for _, problem := range typeErr.Errors {
+			problems = append(problems, lineRegexp.ReplaceAllString(problem, ""))
+		}
+		return problems
+	}
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// merge the overlay into the base document, nested maps are merged and any other value is replaced
+func merge(base, overlay map[interface{}]interface{}) {
+	for key, value := range overlay {
+		baseMap, baseIsMap := base[key].(map[interface{}]interface{})
+		overlayMap, overlayIsMap := value.(map[interface{}]interface{})
+		if baseIsMap && overlayIsMap {
+			merge(baseMap, overlayMap)
+			continue
+		}
+		base[key] = value
+	}
+}
+
+// interpolate replaces the ${VAR} and ${VAR:-default} references in all the string values
+func interpolate(value interface{}) (interface{}, []string) {
+	var problems []string
+	switch v := value.(type) {
+	case string:
+		interpolated := interpolationRegexp.ReplaceAllStringFunc(v, func(reference string) string {
+			match := interpolationRegexp.FindStringSubmatch(reference)
+			if envValue, ok := os.LookupEnv(match[1]); ok {
+				return envValue
+			}
+			if match[2] != "" {
+				return match[3]
+			}
+			problems = append(problems, fmt.Sprintf("environment variable %s referenced in the configuration file is not set", match[1]))
+			return reference
+		})
+		// a value made of a single reference takes the type of the variable, so booleans and numbers can be interpolated
+		if interpolated != v && interpolationRegexp.FindString(v) == v {
+			var typed interface{}
+			if err := yaml.Unmarshal([]byte(interpolated), &typed); err == nil && typed != nil {
+				if !isCollection(typed) {
+					return typed, problems
+				}
+			}
+		}
+		return interpolated, problems
+	case map[interface{}]interface{}:
+		for key, item := range v {
+			interpolated, itemProblems := interpolate(item)
+			v[key] = interpolated
+			problems = append(problems, itemProblems...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			interpolated, itemProblems := interpolate(item)
+			v[i] = interpolated
+			problems = append(problems, itemProblems...)
+		}
+	}
+	return value, problems
+}
+
+func isCollection(value interface{}) bool {
+	switch value.(type) {
+	case map[interface{}]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
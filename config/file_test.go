@@ -0,0 +1,104 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoad_ShouldApplyTheEnvironmentOverlay(t *testing.T) {
+
+	t.Logf("Given a configuration file with dev and prod environments")
+	{
+		t.Logf("\tWhen loading the prod environment")
+		{
+			cfg, err := Load("../data/apigw-pub.yaml", "prod")
+			if err != nil {
+				t.Fatalf("\t\tFailed to load the configuration file %v %v", err, BallotX)
+			}
+
+			if cfg.APIGateway.ID == "nilbbdqvqg" && cfg.APIGateway.Stage == "v2" && cfg.APIGateway.Name == "app-gateway-name" {
+				t.Logf("\t\tThe overlay should be merged into the file settings %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe overlay should be merged into the file settings, got %+v %v", cfg.APIGateway, BallotX)
+			}
+
+			if !cfg.Cors.Enabled {
+				t.Logf("\t\tThe overlay should be able to disable a setting %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe overlay should be able to disable a setting %v", BallotX)
+			}
+
+			if cfg.VPCLinkID == "226jx1" && strings.Contains(cfg.Auth.URL, "api-gateway-authorizer-dev-auth") {
+				t.Logf("\t\tThe default values of the interpolated variables should be used %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe default values of the interpolated variables should be used, got %s %s %v", cfg.VPCLinkID, cfg.Auth.URL, BallotX)
+			}
+		}
+	}
+}
+
+func TestLoad_ShouldInterpolateAndOverrideWithEnvironmentVariables(t *testing.T) {
+
+	t.Logf("Given environment variables are set")
+	{
+		os.Setenv("ENVIRONMENT_NAME", "stage")
+		os.Setenv(StageNameVarKey, "v3")
+		defer os.Unsetenv("ENVIRONMENT_NAME")
+		defer os.Unsetenv(StageNameVarKey)
+
+		cfg, err := Load("../data/apigw-pub.yaml", "dev")
+		if err != nil {
+			t.Fatalf("\t\tFailed to load the configuration file %v %v", err, BallotX)
+		}
+
+		if strings.Contains(cfg.Auth.URL, "api-gateway-authorizer-stage-auth") {
+			t.Logf("\tThe variables referenced in the file should be interpolated %v", CheckMark)
+		} else {
+			t.Errorf("\tThe variables referenced in the file should be interpolated, got %s %v", cfg.Auth.URL, BallotX)
+		}
+
+		if cfg.APIGateway.Stage == "v3" {
+			t.Logf("\tThe environment variables should take precedence over the file %v", CheckMark)
+		} else {
+			t.Errorf("\tThe environment variables should take precedence over the file, got %s %v", cfg.APIGateway.Stage, BallotX)
+		}
+	}
+}
+
+func TestLoad_ShouldReportAllTheSchemaViolations(t *testing.T) {
+
+	t.Logf("Given a configuration file with typos")
+	{
+		file, _ := ioutil.TempFile("", "apigw-pub-*.yaml")
+		defer os.Remove(file.Name())
+		file.WriteString(`
+swaggerUrl: ${UNDEFINED_SWAGGER_URL}
+apiGatway:
+  id: abc
+cors:
+  enabled: maybe
+environments:
+  prod:
+    auth:
+      nmae: authorizer
+`)
+		file.Close()
+
+		_, err := Load(file.Name(), "")
+		validationErr, ok := err.(ValidationError)
+		if !ok {
+			t.Fatalf("\tA validation error should be returned, got %v %v", err, BallotX)
+		}
+
+		expected := []string{"environments.prod: field nmae not found", "UNDEFINED_SWAGGER_URL", "field apiGatway not found", "maybe"}
+		for _, problem := range expected {
+			if strings.Contains(validationErr.Error(), problem) {
+				t.Logf("\tThe problem [%s] should be reported %v", problem, CheckMark)
+			} else {
+				t.Errorf("\tThe problem [%s] should be reported, got %v %v", problem, validationErr, BallotX)
+			}
+		}
+	}
+}
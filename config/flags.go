@@ -0,0 +1,103 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/apigw"
+	"github.com/akhettar/apigw-pub/swagger"
+)
+
+// Bind registers the flags of the given groups, the current settings (read from the configuration file and the environment
+// variables) are used as the flag defaults so a flag always takes precedence
+func (c *Config) Bind(fs *flag.FlagSet, groups ...Group) {
+	for _, group := range groups {
+		switch group {
+		case SourceGroup:
+			c.bindStageVariables(fs)
+			fs.StringVar(&c.SwaggerURL, "swagger-url", c.SwaggerURL, usage("the url of the swagger document, a file path or - for stdin", SwaggerUrl))
+			fs.StringVar(&c.APIGateway.Name, "api-gateway-name", c.APIGateway.Name, usage("the api gateway name", swagger.ApiGwName))
+			fs.StringVar(&c.Auth.Type, "auth-type", c.Auth.Type, usage("the authorizer type: apiKey, cognito or iam", swagger.AuthType))
+			fs.StringVar(&c.Auth.Name, "auth-name", c.Auth.Name, usage("the authorizer name", swagger.AuthName))
+			fs.StringVar(&c.Auth.URL, "auth-url", c.Auth.URL, usage("the authorizer lambda invocation url", swagger.AuthUrl))
+			fs.StringVar(&c.Auth.LambdaAuthorizer.Type, "authorizer-type", c.Auth.LambdaAuthorizer.Type, usage("the lambda authorizer type: token or request", swagger.AuthorizerType))
+			fs.Var((*listValue)(&c.Auth.IdentitySources), "auth-identity-sources", usage("comma separated identity sources of the lambda authorizer, exp: method.request.header.X-Api-Key", swagger.AuthIdentitySources))
+			fs.IntVar(&c.Auth.ResultTTL, "auth-result-ttl", c.Auth.ResultTTL, usage("the seconds the lambda authorizer result is cached", swagger.AuthResultTTL))
+			fs.StringVar(&c.Auth.Credentials, "auth-credentials", c.Auth.Credentials, usage("the role assumed to invoke the lambda authorizer", swagger.AuthCredentials))
+			fs.StringVar(&c.Auth.ValidationExpression, "auth-validation-expression", c.Auth.ValidationExpression, usage("the regular expression the token must match", swagger.AuthValidationExpression))
+			fs.Var((*listValue)(&c.Auth.ProviderARNs), "auth-provider-arns", usage("comma separated arns of the user pools of the cognito authorizer", swagger.AuthProviderARNs))
+			fs.StringVar(&c.RequestValidator, "request-validator", c.RequestValidator, usage("the request validator of the operations: none, body, params or full", swagger.RequestValidator))
+			fs.BoolVar(&c.Cors.Enabled, "cors", c.Cors.Enabled, usage("enables cors on all the endpoints", swagger.CorsEnabled))
+			fs.Var((*listValue)(&c.Cors.AllowOrigins), "cors-allow-origins", usage("comma separated origins allowed to call the api, any origin by default", swagger.CorsAllowOrigins))
+			fs.Var((*listValue)(&c.Cors.AllowMethods), "cors-allow-methods", usage("comma separated methods allowed, the methods of each path by default", swagger.CorsAllowMethods))
+			fs.Var((*listValue)(&c.Cors.AllowHeaders), "cors-allow-headers", usage("comma separated request headers allowed, the custom headers are always allowed", swagger.CorsAllowHeaders))
+			fs.Var((*listValue)(&c.Cors.ExposeHeaders), "cors-expose-headers", usage("comma separated response headers browsers can read", swagger.CorsExposeHeaders))
+			fs.IntVar(&c.Cors.MaxAge, "cors-max-age", c.Cors.MaxAge, usage("the seconds browsers cache the preflight responses", swagger.CorsMaxAge))
+			fs.BoolVar(&c.Cors.AllowCredentials, "cors-allow-credentials", c.Cors.AllowCredentials, usage("allows the requests with credentials, the origins must be listed", swagger.CorsAllowCredentials))
+			fs.StringVar(&c.AccountID, "account-id", c.AccountID, usage("the aws account id of the integrated lambda functions", swagger.AWSAccountID))
+			fs.BoolVar(&c.Proxy.Enabled, "proxy", c.Proxy.Enabled, usage("adds a greedy ANY /{proxy+} route passing all the other requests through to the backend", swagger.ProxyEnabled))
+			fs.StringVar(&c.Proxy.Authorizer, "proxy-authorizer", c.Proxy.Authorizer, usage("the authorizer guarding the greedy route, the default authorizer when not set", swagger.ProxyAuthorizer))
+			fs.BoolVar(&c.Proxy.APIKeyRequired, "proxy-api-key-required", c.Proxy.APIKeyRequired, usage("the greedy route requires an api key of a usage plan", swagger.ProxyAPIKeyRequired))
+			fs.Var((*listValue)(&c.Responses.Headers), "response-headers", usage("comma separated headers passed through from the backend responses", swagger.ResponseHeaders))
+			fs.Var((*listValue)(&c.CustomHeaders), "custom-headers", usage("comma separated headers mapped to the integrations", swagger.CustomHeaders))
+			fs.DurationVar(&c.Fetch.Timeout, "fetch-timeout", c.Fetch.Timeout, usage("the timeout of each attempt to fetch the swagger document", swagger.SwaggerTimeout))
+			fs.IntVar(&c.Fetch.Retries, "fetch-retries", c.Fetch.Retries, usage("the retries on connection errors and 5xx responses when fetching the swagger document", swagger.SwaggerRetries))
+			fs.DurationVar(&c.Fetch.RetryBackoff, "fetch-retry-backoff", c.Fetch.RetryBackoff, usage("the delay before the first retry, doubled on every retry", swagger.SwaggerRetryBackoff))
+			fs.StringVar(&c.Fetch.CABundle, "ca-bundle", c.Fetch.CABundle, usage("the pem file of the certificate authorities trusted to fetch the swagger document", swagger.SwaggerCABundle))
+			fs.StringVar(&c.Fetch.ClientCert, "client-cert", c.Fetch.ClientCert, usage("the pem file of the client certificate presented to fetch the swagger document", swagger.SwaggerClientCert))
+			fs.StringVar(&c.Fetch.ClientKey, "client-key", c.Fetch.ClientKey, usage("the pem file of the client certificate key", swagger.SwaggerClientKey))
+		case GatewayGroup:
+			fs.StringVar(&c.APIGateway.ID, "api-gateway-id", c.APIGateway.ID, usage("the api gateway id", APIGatewayIDKey))
+			fs.StringVar(&c.Region, "region", c.Region, usage("the aws region, defaults to eu-west-1", apigw.Region))
+			fs.StringVar(&c.AssumeRole, "assume-role", c.AssumeRole, usage("the arn of the role assumed to publish to api gateway", apigw.AssumeRole))
+			fs.StringVar(&c.APIGateway.ImportMode, "import-mode", c.APIGateway.ImportMode, usage("the import mode: overwrite replaces the REST API, merge only adds and updates the imported routes", ImportMode))
+			fs.StringVar(&c.APIGateway.Owner, "owner", c.APIGateway.Owner, usage("the owner of the imported routes, its routes no longer published are deleted on merge", Owner))
+		case StageGroup:
+			// the stage variables set on deploy are built from the same settings as the rendered document
+			c.bindStageVariables(fs)
+			fs.StringVar(&c.APIGateway.Stage, "stage", c.APIGateway.Stage, usage("the api gateway stage name", StageNameVarKey))
+			fs.Float64Var(&c.Canary.Percent, "canary-percent", c.Canary.Percent, usage("the percentage of the traffic sent to the new deployment, deploys a canary when set", apigw.CanaryPercent))
+			fs.StringVar(&c.Canary.HealthCheck.URL, "health-check-url", c.Canary.HealthCheck.URL, usage("the endpoint polled through the stage before the canary is promoted", apigw.HealthCheckURL))
+			fs.StringVar(&c.UsagePlans.KeysFile, "api-keys-file", c.UsagePlans.KeysFile, usage("the csv file of the api keys provisioned with the usage plans", apigw.APIKeysFile))
+		case OutputGroup:
+			fs.StringVar(&c.Output, "output", c.Output, usage("the file the rendered swagger is written to, - for stdout", DryRunOutput))
+		case DiffGroup:
+			fs.StringVar(&c.Diff.Format, "diff-format", c.Diff.Format, usage("the diff output format: text or json", DiffFormat))
+			fs.BoolVar(&c.Diff.FailOnRouteRemoval, "fail-on-route-removal", c.Diff.FailOnRouteRemoval, usage("fails when a deployed route would be removed", FailOnRemoval))
+		}
+	}
+}
+
+// bindStageVariables registers the flags of the settings the stage variables are built from, once when several groups
+// need them
+func (c *Config) bindStageVariables(fs *flag.FlagSet) {
+	if fs.Lookup("stage-variables") != nil {
+		return
+	}
+	fs.StringVar(&c.EndpointURL, "endpoint-url", c.EndpointURL, usage("the host and base path of the service, with an optional scheme, defaults to the swagger host and basePath", swagger.EndpointUrl))
+	fs.StringVar(&c.EndpointScheme, "endpoint-scheme", c.EndpointScheme, usage("the scheme of the service when the endpoint url has none: http or https", swagger.EndpointScheme))
+	fs.StringVar(&c.EndpointPort, "endpoint-port", c.EndpointPort, usage("the port of the service when the endpoint url has none", swagger.EndpointPort))
+	fs.StringVar(&c.ConnectionType, "connection-type", c.ConnectionType, usage("the integration connection type: PUBLIC or VPC_LINK", swagger.ConnectionType))
+	fs.StringVar(&c.VPCLinkID, "vpc-link-id", c.VPCLinkID, usage("the vpc link id, required for the VPC_LINK connection type", swagger.VPCLinkID))
+	fs.BoolVar(&c.StageVariables.Enabled, "stage-variables", c.StageVariables.Enabled, usage("the integrations reference the endpointUrl and vpcLinkId stage variables set when deploying", swagger.StageVariablesEnabled))
+}
+
+func usage(description, envKey string) string {
+	return fmt.Sprintf("%s (env %s)", description, envKey)
+}
+
+// listValue a comma separated list flag
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = swagger.SplitList(value)
+	return nil
+}
@@ -0,0 +1,375 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/swagger"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+
+	Resource    = "resource"
+	Method      = "method"
+	Integration = "integration"
+	Authorizer  = "authorizer"
+	Model       = "model"
+
+	IntegrationExtension = "x-amazon-apigateway-integration"
+	AuthorizerExtension  = "x-amazon-apigateway-authorizer"
+	InternetConnection   = "INTERNET"
+)
+
+// the integration fields compared, API Gateway exports a number of defaulted fields which are not rendered by this tool
+var integrationFields = []string{"type", "uri", "httpMethod", "connectionType", "connectionId", "passthroughBehavior", "requestParameters"}
+
+// Change a single difference between the deployed and the rendered documents
+type Change struct {
+	Kind     string `json:"kind"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Report all the differences between the deployed and the rendered documents
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Compare the currently deployed swagger document with the one about to be imported
+func Compare(current, desired swg.Swagger) Report {
+	var report Report
+	currentPaths := paths(current)
+	desiredPaths := paths(desired)
+
+	for key, desiredItem := range desiredPaths {
+		currentItem, ok := currentPaths[key]
+		if !ok {
+			report.add(Added, Resource, key, "")
+			for method := range operations(desiredItem) {
+				report.add(Added, Method, route(method, key), "")
+			}
+			continue
+		}
+		report.comparePath(key, currentItem, desiredItem)
+	}
+
+	for key, currentItem := range currentPaths {
+		if _, ok := desiredPaths[key]; ok {
+			continue
+		}
+		report.add(Removed, Resource, key, "")
+		for method := range operations(currentItem) {
+			report.add(Removed, Method, route(method, key), "")
+		}
+	}
+
+	report.compareAuthorizers(current.SecurityDefinitions, desired.SecurityDefinitions)
+	report.compareModels(current.Definitions, desired.Definitions)
+	report.sort()
+	return report
+}
+
+// RemovedRoutes returns the methods which would be deleted by the import
+func (report Report) RemovedRoutes() []string {
+	var routes []string
+	for _, change := range report.Changes {
+		if change.Kind == Removed && change.Category == Method {
+			routes = append(routes, change.Name)
+		}
+	}
+	return routes
+}
+
+// Merged returns the changes of an import in merge mode: the deployed methods missing from the rendered document are
+// only removed when they are in the given routes, every other removal is dropped
+func (report Report) Merged(removedRoutes []string) Report {
+	removed := map[string]bool{}
+	for _, route := range removedRoutes {
+		removed[route] = true
+	}
+	// the resources keeping at least one method
+	kept := map[string]bool{}
+	for _, change := range report.Changes {
+		if change.Kind == Removed && change.Category == Method && !removed[change.Name] {
+			kept[strings.SplitN(change.Name, " ", 2)[1]] = true
+		}
+	}
+
+	var merged Report
+	for _, change := range report.Changes {
+		if change.Kind == Removed {
+			switch {
+			case change.Category == Method && !removed[change.Name]:
+				continue
+			case change.Category == Resource && kept[change.Name]:
+				continue
+			case change.Category != Method && change.Category != Resource:
+				continue
+			}
+		}
+		merged.Changes = append(merged.Changes, change)
+	}
+	return merged
+}
+
+// HasChanges true when the rendered document differs from the deployed one
+func (report Report) HasChanges() bool {
+	return len(report.Changes) > 0
+}
+
+// JSON renders the report in json
+func (report Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// String renders the report in a human readable form
+func (report Report) String() string {
+	if !report.HasChanges() {
+		return "No changes\n"
+	}
+	symbols := map[string]string{Added: "+", Removed: "-", Changed: "~"}
+
+	var builder strings.Builder
+	for _, change := range report.Changes {
+		fmt.Fprintf(&builder, "%s %-11s %s", symbols[change.Kind], change.Category, change.Name)
+		if change.Detail != "" {
+			fmt.Fprintf(&builder, " (%s)", change.Detail)
+		}
+		builder.WriteString("\n")
+	}
+	fmt.Fprintf(&builder, "%d change(s), %d route(s) removed\n", len(report.Changes), len(report.RemovedRoutes()))
+	return builder.String()
+}
+
+func (report *Report) add(kind, category, name, detail string) {
+	report.Changes = append(report.Changes, Change{Kind: kind, Category: category, Name: name, Detail: detail})
+}
+
+func (report *Report) comparePath(key string, currentItem, desiredItem swg.PathItem) {
+	currentOps := operations(currentItem)
+	desiredOps := operations(desiredItem)
+
+	for method, desiredOp := range desiredOps {
+		currentOp, ok := currentOps[method]
+		if !ok {
+			report.add(Added, Method, route(method, key), "")
+			continue
+		}
+
+		if currentSecurity, desiredSecurity := securityNames(currentOp), securityNames(desiredOp); currentSecurity != desiredSecurity {
+			report.add(Changed, Method, route(method, key), fmt.Sprintf("security: %q -> %q", currentSecurity, desiredSecurity))
+		}
+		if fields := changedFields(integration(currentOp), integration(desiredOp)); len(fields) > 0 {
+			report.add(Changed, Integration, route(method, key), strings.Join(fields, ", "))
+		}
+	}
+
+	for method := range currentOps {
+		if _, ok := desiredOps[method]; !ok {
+			report.add(Removed, Method, route(method, key), "")
+		}
+	}
+}
+
+func (report *Report) compareAuthorizers(current, desired swg.SecurityDefinitions) {
+	for name, desiredScheme := range desired {
+		currentScheme, ok := current[name]
+		if !ok {
+			report.add(Added, Authorizer, name, "")
+			continue
+		}
+		if !equal(currentScheme.Extensions[AuthorizerExtension], desiredScheme.Extensions[AuthorizerExtension]) {
+			report.add(Changed, Authorizer, name, "")
+		}
+	}
+	for name := range current {
+		if _, ok := desired[name]; !ok {
+			report.add(Removed, Authorizer, name, "")
+		}
+	}
+}
+
+func (report *Report) compareModels(current, desired swg.Definitions) {
+	for name, desiredSchema := range desired {
+		currentSchema, ok := current[name]
+		if !ok {
+			report.add(Added, Model, name, "")
+			continue
+		}
+		if !reflect.DeepEqual(modelShape(currentSchema), modelShape(desiredSchema)) {
+			report.add(Changed, Model, name, "")
+		}
+	}
+	for name := range current {
+		if _, ok := desired[name]; !ok {
+			report.add(Removed, Model, name, "")
+		}
+	}
+}
+
+// resources are listed first, then methods, integrations, authorizers and models
+func (report *Report) sort() {
+	order := map[string]int{Resource: 0, Method: 1, Integration: 2, Authorizer: 3, Model: 4}
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		left, right := report.Changes[i], report.Changes[j]
+		if left.Category != right.Category {
+			return order[left.Category] < order[right.Category]
+		}
+		if left.Name != right.Name {
+			return left.Name < right.Name
+		}
+		return left.Kind < right.Kind
+	})
+}
+
+func paths(doc swg.Swagger) map[string]swg.PathItem {
+	if doc.Paths == nil {
+		return map[string]swg.PathItem{}
+	}
+	return doc.Paths.Paths
+}
+
+func operations(item swg.PathItem) map[string]*swg.Operation {
+	ops := map[string]*swg.Operation{}
+	for _, operation := range swagger.Operations(item) {
+		ops[operation.Method] = operation.Operation
+	}
+	return ops
+}
+
+func route(method, key string) string {
+	return fmt.Sprintf("%s %s", method, key)
+}
+
+// securityNames returns the authorizers of the operation, the iam auth is set with an extension rather than a requirement
+func securityNames(op *swg.Operation) string {
+	var names []string
+	for _, requirement := range op.Security {
+		for name := range requirement {
+			names = append(names, name)
+		}
+	}
+	var auth struct {
+		Type string `json:"type"`
+	}
+	if err := remarshal(op.Extensions[swagger.IAMAuthExtension], &auth); err == nil && auth.Type != "" {
+		names = append(names, auth.Type)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// integration returns the compared fields of the operation integration in a normalised form
+func integration(op *swg.Operation) map[string]interface{} {
+	var raw map[string]interface{}
+	if err := remarshal(op.Extensions[IntegrationExtension], &raw); err != nil || raw == nil {
+		return map[string]interface{}{}
+	}
+
+	fields := map[string]interface{}{}
+	for _, field := range integrationFields {
+		value, ok := raw[field]
+		if !ok || value == "" {
+			continue
+		}
+		if str, isString := value.(string); isString && field != "uri" && field != "connectionId" {
+			value = strings.ToUpper(str)
+		}
+		fields[field] = value
+	}
+
+	// the internet connection type is not exported by API Gateway and rendered as PUBLIC by this tool
+	if connection, ok := fields["connectionType"]; ok && (connection == "PUBLIC" || connection == InternetConnection) {
+		delete(fields, "connectionType")
+	}
+	if requestParameters, ok := fields["requestParameters"].(map[string]interface{}); ok && len(requestParameters) == 0 {
+		delete(fields, "requestParameters")
+	}
+	return fields
+}
+
+func changedFields(current, desired map[string]interface{}) []string {
+	var fields []string
+	for _, field := range integrationFields {
+		if !reflect.DeepEqual(current[field], desired[field]) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// modelShape returns the compared fields of the model: API Gateway adds a title to the exported models and rewrites
+// their references, only the type, properties, required and items are compared with the references reduced to the
+// model name
+func modelShape(schema swg.Schema) interface{} {
+	var raw interface{}
+	if err := remarshal(schema, &raw); err != nil {
+		return nil
+	}
+	return normaliseSchema(raw)
+}
+
+func normaliseSchema(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		schemas := make([]interface{}, len(value))
+		for i, schema := range value {
+			schemas[i] = normaliseSchema(schema)
+		}
+		return schemas
+	case map[string]interface{}:
+		shape := map[string]interface{}{}
+		if ref, ok := value["$ref"].(string); ok {
+			shape["$ref"] = ref[strings.LastIndex(ref, "/")+1:]
+		}
+		if schemaType, ok := value["type"]; ok {
+			shape["type"] = schemaType
+		}
+		if properties, ok := value["properties"].(map[string]interface{}); ok {
+			normalised := map[string]interface{}{}
+			for name, property := range properties {
+				normalised[name] = normaliseSchema(property)
+			}
+			shape["properties"] = normalised
+		}
+		if required, ok := value["required"].([]interface{}); ok {
+			var names []string
+			for _, name := range required {
+				names = append(names, fmt.Sprint(name))
+			}
+			sort.Strings(names)
+			shape["required"] = names
+		}
+		if items, ok := value["items"]; ok {
+			shape["items"] = normaliseSchema(items)
+		}
+		return shape
+	default:
+		return value
+	}
+}
+
+// compares the json representation of both values, the exported document is decoded from json too
+func equal(current, desired interface{}) bool {
+	var left, right interface{}
+	if remarshal(current, &left) != nil || remarshal(desired, &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func remarshal(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/akhettar/apigw-pub/swagger"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// CheckMark used for unit test highlight.
+	CheckMark = "\u2713"
+
+	// BallotX used for unit test highlight.
+	BallotX = "\u2717"
+)
+
+func readSwagger(t *testing.T) swg.Swagger {
+	var doc swg.Swagger
+	data, _ := ioutil.ReadFile("../data/swagger.json")
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("\t\tFailed to read the swagger document %v %v", err, BallotX)
+	}
+	return doc
+}
+
+func TestCompare_ShouldReportNoChangesForTheSameDocument(t *testing.T) {
+
+	t.Logf("Given the deployed swagger is the same as the rendered one")
+	{
+		report := Compare(readSwagger(t), readSwagger(t))
+		if !report.HasChanges() {
+			t.Logf("\tThe report should not have any change %v", CheckMark)
+		} else {
+			t.Errorf("\tThe report should not have any change, got %v %v", report, BallotX)
+		}
+	}
+}
+
+func TestCompare_ShouldReportAddedRemovedAndChangedItems(t *testing.T) {
+
+	t.Logf("Given the rendered swagger differs from the deployed one")
+	{
+		current := readSwagger(t)
+		desired := readSwagger(t)
+
+		delete(desired.Paths.Paths, "/admin/accounts/{accountId}")
+		item := desired.Paths.Paths["/accounts/{accountId}"]
+		item.Head = swg.NewOperation("headAccount")
+		desired.Paths.Paths["/accounts/{accountId}"] = item
+		desired.Definitions["NewDto"] = swg.Schema{}
+
+		item = desired.Paths.Paths["/accounts/{accountId}/status"]
+		for _, op := range []*swg.Operation{item.Get, item.Put, item.Post, item.Patch, item.Delete} {
+			if op != nil {
+				op.AddExtension(IntegrationExtension, map[string]interface{}{"type": "http", "uri": "http://new-host/status"})
+			}
+		}
+
+		report := Compare(current, desired)
+
+		t.Logf("\tWhen comparing the documents")
+		{
+			expected := []string{
+				"- resource    /admin/accounts/{accountId}",
+				"+ method      HEAD /accounts/{accountId}",
+				"~ integration",
+				"+ model       NewDto",
+			}
+			for _, line := range expected {
+				if strings.Contains(report.String(), line) {
+					t.Logf("\t\tThe report should contain [%s] %v", line, CheckMark)
+				} else {
+					t.Errorf("\t\tThe report should contain [%s], got\n%v %v", line, report, BallotX)
+				}
+			}
+
+			removed := report.RemovedRoutes()
+			if len(removed) > 0 && strings.HasSuffix(removed[0], " /admin/accounts/{accountId}") {
+				t.Logf("\t\tThe methods of the removed resource should be reported as removed routes %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe methods of the removed resource should be reported as removed routes, got %v %v", removed, BallotX)
+			}
+
+			data, err := report.JSON()
+			var decoded Report
+			if err == nil && json.Unmarshal(data, &decoded) == nil && len(decoded.Changes) == len(report.Changes) {
+				t.Logf("\t\tThe report should be rendered in json %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe report should be rendered in json %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestMerged_ShouldOnlyKeepTheRemovalsOfTheGivenRoutes(t *testing.T) {
+
+	t.Logf("Given the rendered swagger only holds a part of the deployed routes")
+	{
+		current := readSwagger(t)
+		desired := readSwagger(t)
+		delete(desired.Paths.Paths, "/admin/accounts/{accountId}")
+		delete(desired.Paths.Paths, "/organisations/{orgId}")
+		delete(desired.Definitions, "OrganisationDto")
+
+		t.Logf("\tWhen scoping the changes to a merge import removing the organisation GET route")
+		{
+			report := Compare(current, desired).Merged([]string{"GET /organisations/{orgId}"})
+
+			removed := report.RemovedRoutes()
+			if len(removed) == 1 && removed[0] == "GET /organisations/{orgId}" {
+				t.Logf("\t\tOnly the given route should be removed %v", CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the given route should be removed, got %v %v", removed, BallotX)
+			}
+
+			if !strings.Contains(report.String(), "- resource") && !strings.Contains(report.String(), "- model") {
+				t.Logf("\t\tThe resources keeping a method and the models should not be removed %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe resources keeping a method and the models should not be removed, got\n%v %v", report, BallotX)
+			}
+		}
+	}
+}
+
+func TestCompare_ShouldReportTheIAMAuthOfAnOperation(t *testing.T) {
+
+	t.Logf("Given the rendered swagger secures an operation with the iam auth")
+	{
+		current := readSwagger(t)
+		desired := readSwagger(t)
+		op := desired.Paths.Paths["/accounts/{accountId}"].Get
+		op.Security = nil
+		op.AddExtension(swagger.IAMAuthExtension, map[string]string{"type": "AWS_IAM"})
+
+		t.Logf("\tWhen comparing the documents, the security change should be reported")
+		{
+			report := Compare(current, desired)
+			if line := "~ method      GET /accounts/{accountId}"; strings.Contains(report.String(), line) && strings.Contains(report.String(), "AWS_IAM") {
+				t.Logf("\t\tThe report should contain [%s] %v", line, CheckMark)
+			} else {
+				t.Errorf("\t\tThe report should contain [%s], got\n%v %v", line, report, BallotX)
+			}
+		}
+	}
+}
+
+func TestCompare_ShouldOnlyReportTheChangedModelsOfAnExport(t *testing.T) {
+
+	t.Logf("Given the deployed models are exported by API Gateway with a title and rewritten references")
+	{
+		var exported swg.Swagger
+		data, _ := ioutil.ReadFile("../data/swagger_export.json")
+		if err := json.Unmarshal(data, &exported); err != nil {
+			t.Fatalf("\t\tFailed to read the exported swagger document %v %v", err, BallotX)
+		}
+		rendered := readSwagger(t)
+		desired := swg.Swagger{SwaggerProps: swg.SwaggerProps{Definitions: swg.Definitions{
+			"AccountDto": rendered.Definitions["AccountDto"],
+			"AddressDto": rendered.Definitions["AddressDto"],
+		}}}
+
+		t.Logf("\tWhen comparing the documents, only the model whose postcode type changed should be reported")
+		{
+			report := Compare(exported, desired)
+			if len(report.Changes) == 1 && report.Changes[0].Name == "AddressDto" {
+				t.Logf("\t\tOnly the AddressDto model should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the AddressDto model should be reported, got\n%v %v", report, BallotX)
+			}
+		}
+	}
+}
@@ -5,5 +5,7 @@ go 1.14
 require (
 	github.com/aws/aws-sdk-go v1.30.17
 	github.com/go-openapi/spec v0.19.7
+	github.com/go-openapi/swag v0.19.5
 	github.com/sirupsen/logrus v1.5.0
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -11,4 +11,38 @@ type AWSAPIGatewayIntegration struct {
 	RequestParameters   map[string]string                 `json:"requestParameters"`
 	RequestTemplates    map[string]string                 `json:"requestTemplates"`
 	Responses           map[string]map[string]interface{} `json:"responses"`
+	Credentials         string                            `json:"credentials,omitempty"`
+	TimeoutInMillis     int                               `json:"timeoutInMillis,omitempty"`
+	CacheKeyParameters  []string                          `json:"cacheKeyParameters,omitempty"`
+	CacheNamespace      string                            `json:"cacheNamespace,omitempty"`
+}
+
+// AWSAPIGatewayRequestValidator an entry of the x-amazon-apigateway-request-validators extension, the parts of the request
+// API Gateway validates before calling the integration
+type AWSAPIGatewayRequestValidator struct {
+	ValidateRequestBody       bool `json:"validateRequestBody"`
+	ValidateRequestParameters bool `json:"validateRequestParameters"`
+}
+
+// AWSAPIGatewayGatewayResponse an entry of the x-amazon-apigateway-gateway-responses extension, the response API Gateway
+// returns when it rejects a request without calling the integration
+type AWSAPIGatewayGatewayResponse struct {
+	StatusCode         string            `json:"statusCode,omitempty"`
+	ResponseParameters map[string]string `json:"responseParameters,omitempty"`
+	ResponseTemplates  map[string]string `json:"responseTemplates,omitempty"`
+}
+
+// AWSAPIGatewayPolicy the x-amazon-apigateway-policy extension, the resource policy of the API
+type AWSAPIGatewayPolicy struct {
+	Version   string                         `json:"Version"`
+	Statement []AWSAPIGatewayPolicyStatement `json:"Statement"`
+}
+
+// AWSAPIGatewayPolicyStatement a statement of the resource policy
+type AWSAPIGatewayPolicyStatement struct {
+	Effect    string                            `json:"Effect"`
+	Principal interface{}                       `json:"Principal"`
+	Action    string                            `json:"Action"`
+	Resource  string                            `json:"Resource"`
+	Condition map[string]map[string]interface{} `json:"Condition,omitempty"`
 }
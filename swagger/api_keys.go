@@ -0,0 +1,48 @@
+package swagger
+
+import (
+	"strconv"
+
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// APIKeyRequiredExtension the extension of an operation or path requiring the callers to send an api key of a usage
+	// plan in the x-api-key header
+	APIKeyRequiredExtension = "x-api-key-required"
+
+	apiKeySchemeName = "api_key"
+	apiKeyHeader     = "x-api-key"
+)
+
+// isAPIKeyRequired tells whether the extension of the operation, or else of its path, is set to true
+func isAPIKeyRequired(path swg.PathItem, operation Operation) bool {
+	for _, extensions := range []swg.Extensions{operation.Extensions, path.Extensions} {
+		switch value := extensions[APIKeyRequiredExtension].(type) {
+		case bool:
+			return value
+		case string:
+			required, err := strconv.ParseBool(value)
+			return err == nil && required
+		}
+	}
+	return false
+}
+
+// requireAPIKey adds the api key to the security requirements of the operation, along with its authorizer if any
+func requireAPIKey(op *swg.Operation) {
+	if len(op.Security) == 0 {
+		op.Security = []map[string][]string{{}}
+	}
+	for _, requirement := range op.Security {
+		requirement[apiKeySchemeName] = []string{}
+	}
+}
+
+// addAPIKeyDefinition adds the security definition of the api key the operations require
+func addAPIKeyDefinition(doc *swg.Swagger) {
+	if doc.SecurityDefinitions == nil {
+		doc.SecurityDefinitions = map[string]*swg.SecurityScheme{}
+	}
+	doc.SecurityDefinitions[apiKeySchemeName] = swg.APIKeyAuth(apiKeyHeader, "header")
+}
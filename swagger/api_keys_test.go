@@ -0,0 +1,53 @@
+package swagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldRequireTheAPIKeyOfTheMarkedOperations(t *testing.T) {
+
+	t.Logf("Given a path and an unsecured operation requiring an api key")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/organisations/{orgId}"]
+		item.AddExtension(APIKeyRequiredExtension, true)
+		doc.Paths.Paths["/organisations/{orgId}"] = item
+		doc.Paths.Paths["/accounts/{accountId}/status"].Get.AddExtension(APIKeyRequiredExtension, "true")
+		doc.Paths.Paths["/accounts/{accountId}/status"].Get.AddExtension(AuthDisabledExtension, "true")
+
+		t.Logf("\tWhen rendering the swagger, the operations should require the key")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", OptionsFromEnv()).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			if scheme := rendered.SecurityDefinitions["api_key"]; scheme != nil && scheme.Name == "x-api-key" {
+				t.Logf("\t\tThe api key should be defined %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe api key should be defined, got %v %v", rendered.SecurityDefinitions, BallotX)
+			}
+			expected := map[*swg.Operation][]map[string][]string{
+				rendered.Paths.Paths["/organisations/{orgId}"].Get:       {{ExpectedAuthorizerName: {}, "api_key": {}}},
+				rendered.Paths.Paths["/accounts/{accountId}/status"].Get: {{"api_key": {}}},
+				rendered.Paths.Paths["/accounts/{accountId}"].Get:        {{ExpectedAuthorizerName: {}}},
+			}
+			for op, security := range expected {
+				if reflect.DeepEqual(op.Security, security) {
+					t.Logf("\t\tThe operation %s should be secured with %v %v", op.ID, security, CheckMark)
+				} else {
+					t.Errorf("\t\tThe operation %s should be secured with %v, got %v %v", op.ID, security, op.Security, BallotX)
+				}
+			}
+			if _, ok := rendered.Paths.Paths["/organisations/{orgId}"].Extensions[APIKeyRequiredExtension]; ok {
+				t.Errorf("\t\tThe %s extension should be removed %v", APIKeyRequiredExtension, BallotX)
+			}
+		}
+	}
+}
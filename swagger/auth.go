@@ -0,0 +1,437 @@
+package swagger
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// CognitoAuth the auth type of the cognito user pool authorizer
+	CognitoAuth = "cognito"
+	// IAMAuth the auth type of the operations signed with SigV4
+	IAMAuth          = "iam"
+	IAMAuthExtension = "x-amazon-apigateway-auth"
+	AuthProviderARNs = "AUTH_PROVIDER_ARNS"
+
+	// AuthorizerExtension the extension of an operation or path naming its authorizer
+	AuthorizerExtension   = "x-authorizer"
+	AuthDisabledExtension = "x-auth-disabled"
+
+	cognitoAuthType  = "cognito_user_pools"
+	iamAuthType      = "AWS_IAM"
+	cognitoArnPrefix = "arn:aws:cognito-idp:"
+
+	AuthorizerType           = "AUTH_AUTHORIZER_TYPE"
+	AuthIdentitySources      = "AUTH_IDENTITY_SOURCES"
+	AuthResultTTL            = "AUTH_RESULT_TTL"
+	AuthCredentials          = "AUTH_CREDENTIALS"
+	AuthValidationExpression = "AUTH_VALIDATION_EXPRESSION"
+
+	// TokenAuthorizer and RequestAuthorizer the types of the lambda authorizer of the custom auth
+	TokenAuthorizer   = "token"
+	RequestAuthorizer = "request"
+
+	headerSource      = "method.request.header."
+	maxResultTTL      = 3600
+	iamArnPrefix      = "arn:aws:iam::"
+	iamRoleArnPrefix  = iamArnPrefix
+	authorizationName = "Authorization"
+)
+
+// identitySourcePrefixes the identity sources of a request authorizer
+var identitySourcePrefixes = []string{headerSource, "method.request.querystring.", "stageVariables.", "context."}
+
+// LambdaAuthorizer the settings of the lambda authorizer of the custom auth
+type LambdaAuthorizer struct {
+	// Type token, the default, or request
+	Type string `json:"type,omitempty" yaml:"authorizerType"`
+	// IdentitySources the request parameters identifying the caller, exp: method.request.header.X-Api-Key, the token
+	// authorizer reads a single header, Authorization by default
+	IdentitySources []string `json:"identitySources,omitempty" yaml:"identitySources"`
+	// ResultTTL the seconds the policy returned is cached for the identity, 0 disables the caching
+	ResultTTL int `json:"resultTtl,omitempty" yaml:"resultTtl"`
+	// Credentials the role API Gateway assumes to invoke the authorizer
+	Credentials string `json:"credentials,omitempty" yaml:"credentials"`
+	// ValidationExpression the regular expression the token must match before the authorizer is invoked
+	ValidationExpression string `json:"validationExpression,omitempty" yaml:"validationExpression"`
+}
+
+// ApplyEnv overrides the settings with the environment variables set, the problems of their values are returned
+func (a *LambdaAuthorizer) ApplyEnv() []string {
+	var problems []string
+	for name, value := range map[string]*string{
+		AuthorizerType:           &a.Type,
+		AuthCredentials:          &a.Credentials,
+		AuthValidationExpression: &a.ValidationExpression,
+	} {
+		if v, ok := os.LookupEnv(name); ok {
+			*value = v
+		}
+	}
+	if value, ok := os.LookupEnv(AuthIdentitySources); ok {
+		a.IdentitySources = SplitList(value)
+	}
+	if value, ok := os.LookupEnv(AuthResultTTL); ok {
+		ttl, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid authorizer result ttl (%s) %q, expected a number of seconds", AuthResultTTL, value))
+		}
+		a.ResultTTL = ttl
+	}
+	return problems
+}
+
+// Validate returns the problems of the settings
+func (a LambdaAuthorizer) Validate() []string {
+	var problems []string
+	switch a.authorizerType() {
+	case TokenAuthorizer:
+		if len(a.IdentitySources) > 1 || len(a.IdentitySources) == 1 && !isHeaderSource(a.IdentitySources[0]) {
+			problems = append(problems, fmt.Sprintf("the %s authorizer reads a single header, got %s", TokenAuthorizer, strings.Join(a.IdentitySources, ", ")))
+		}
+		if _, err := regexp.Compile(a.ValidationExpression); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid authorizer validation expression %q: %s", a.ValidationExpression, err))
+		}
+	case RequestAuthorizer:
+		if len(a.IdentitySources) == 0 {
+			problems = append(problems, fmt.Sprintf("the identity sources (--auth-identity-sources or %s) are required for the %s authorizer", AuthIdentitySources, RequestAuthorizer))
+		}
+		if a.ValidationExpression != "" {
+			problems = append(problems, fmt.Sprintf("the validation expression is only supported by the %s authorizer", TokenAuthorizer))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported authorizer type (--authorizer-type or %s) %q, expected one of %s, %s", AuthorizerType, a.Type, TokenAuthorizer, RequestAuthorizer))
+	}
+	for _, source := range a.IdentitySources {
+		if !isIdentitySource(source) {
+			problems = append(problems, fmt.Sprintf("invalid identity source %q, expected one of %s...", source, strings.Join(identitySourcePrefixes, "..., ")))
+		}
+	}
+	if a.ResultTTL < 0 || a.ResultTTL > maxResultTTL {
+		problems = append(problems, fmt.Sprintf("the authorizer result ttl %d must be between 0 and %d seconds", a.ResultTTL, maxResultTTL))
+	}
+	if a.Credentials != "" && !strings.HasPrefix(a.Credentials, iamRoleArnPrefix) {
+		problems = append(problems, fmt.Sprintf("invalid authorizer credentials %q, expected a role arn %s...", a.Credentials, iamRoleArnPrefix))
+	}
+	return problems
+}
+
+func (a LambdaAuthorizer) authorizerType() string {
+	if a.Type == "" {
+		return TokenAuthorizer
+	}
+	return strings.ToLower(a.Type)
+}
+
+// identitySource the comma separated identity sources, the Authorization header by default for the token authorizer
+func (a LambdaAuthorizer) identitySource() string {
+	if len(a.IdentitySources) == 0 {
+		return headerSource + authorizationName
+	}
+	return strings.Join(a.IdentitySources, ",")
+}
+
+// header the header of the security scheme, the first header identity source
+func (a LambdaAuthorizer) header() string {
+	if len(a.IdentitySources) == 0 {
+		return authorizationName
+	}
+	for _, source := range a.IdentitySources {
+		if isHeaderSource(source) {
+			return strings.TrimPrefix(source, headerSource)
+		}
+	}
+	// the scheme requires a header although the request authorizer does not read it
+	return "Unused"
+}
+
+func isHeaderSource(source string) bool {
+	return strings.HasPrefix(source, headerSource)
+}
+
+func isIdentitySource(source string) bool {
+	for _, prefix := range identitySourcePrefixes {
+		if strings.HasPrefix(source, prefix) && len(source) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorizer a named authorizer, the operations select it with their security requirements or x-authorizer extension
+type Authorizer struct {
+	Name string `yaml:"name"`
+	// Type apiKey, a lambda authorizer, cognito or iam
+	Type string `yaml:"type"`
+	// URL the invocation url of the lambda of the apiKey authorizer
+	URL string `yaml:"url"`
+	// ProviderARNs the user pools of the cognito authorizer
+	ProviderARNs []string `yaml:"providerArns"`
+	// the lambda authorizer of the apiKey authorizer
+	LambdaAuthorizer `yaml:",inline"`
+
+	// variables the environment variables of the default authorizer, quoted in its problems
+	variables bool
+}
+
+// authorization the authorizer securing an operation and the scopes it requires, IAM when the callers sign the requests
+type authorization struct {
+	Authorizer string
+	Scopes     []string
+	IAM        bool
+}
+
+func (a authorization) secured() bool {
+	return a.Authorizer != "" || a.IAM
+}
+
+func (a Authorizer) isCustom() bool {
+	return strings.ToLower(a.Type) == strings.ToLower(CustomAuth)
+}
+
+func (a Authorizer) isCognito() bool {
+	return strings.ToLower(a.Type) == CognitoAuth
+}
+
+func (a Authorizer) isIAM() bool {
+	return strings.ToLower(a.Type) == IAMAuth
+}
+
+// Validate returns the problems of the authorizer
+func (a Authorizer) Validate() []string {
+	var problems []string
+	if a.Name == "" {
+		problems = append(problems, fmt.Sprintf("the authorizer name%s is required", a.variable("auth-name", AuthName)))
+	}
+	switch {
+	case a.Type == "":
+		problems = append(problems, fmt.Sprintf("the type%s of the authorizer %q is required, expected one of %s, %s, %s", a.variable("auth-type", AuthType), a.Name, CustomAuth, CognitoAuth, IAMAuth))
+	case a.isCustom():
+		if a.URL == "" {
+			problems = append(problems, fmt.Sprintf("the url%s of the %s authorizer %q is required", a.variable("auth-url", AuthUrl), CustomAuth, a.Name))
+		}
+		problems = append(problems, a.LambdaAuthorizer.Validate()...)
+	case a.isIAM():
+	case a.isCognito():
+		if len(a.ProviderARNs) == 0 {
+			problems = append(problems, fmt.Sprintf("the user pool arns%s of the %s authorizer %q are required", a.variable("auth-provider-arns", AuthProviderARNs), CognitoAuth, a.Name))
+		}
+		for _, arn := range a.ProviderARNs {
+			if !strings.HasPrefix(arn, cognitoArnPrefix) {
+				problems = append(problems, fmt.Sprintf("invalid user pool arn %q, expected %s...", arn, cognitoArnPrefix))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported type%s %q of the authorizer %q, expected one of %s, %s, %s", a.variable("auth-type", AuthType), a.Type, a.Name, CustomAuth, CognitoAuth, IAMAuth))
+	}
+	return problems
+}
+
+// variable names the flag and the environment variable of the setting of the default authorizer
+func (a Authorizer) variable(flag string, name string) string {
+	if !a.variables {
+		return ""
+	}
+	return fmt.Sprintf(" (--%s or %s)", flag, name)
+}
+
+// securityScheme returns the security definition of the authorizer
+func (a Authorizer) securityScheme() *swg.SecurityScheme {
+	if a.isCognito() {
+		// the cognito authorizer validates the token of the Authorization header
+		scheme := swg.APIKeyAuth(authorizationName, "header")
+		scheme.AddExtension("x-amazon-apigateway-authtype", cognitoAuthType)
+		scheme.AddExtension("x-amazon-apigateway-authorizer", map[string]interface{}{
+			"type":         cognitoAuthType,
+			"providerARNs": a.ProviderARNs,
+		})
+		return scheme
+	}
+
+	// the lambda authorizer is invoked with the identity sources of the request
+	lambda := a.LambdaAuthorizer
+	authorizer := map[string]interface{}{
+		"type":                         lambda.authorizerType(),
+		"authorizerUri":                a.URL,
+		"authorizerResultTtlInSeconds": lambda.ResultTTL,
+		"identitySource":               lambda.identitySource(),
+	}
+	if lambda.Credentials != "" {
+		authorizer["authorizerCredentials"] = lambda.Credentials
+	}
+	if lambda.ValidationExpression != "" {
+		authorizer["identityValidationExpression"] = lambda.ValidationExpression
+	}
+	scheme := swg.APIKeyAuth(lambda.header(), "header")
+	scheme.AddExtension("x-amazon-apigateway-authtype", "custom")
+	scheme.AddExtension("x-amazon-apigateway-authorizer", authorizer)
+	return scheme
+}
+
+// authorizer the default authorizer of the operations, set with the AUTH_* environment variables. The iam authorizer
+// is named after its type when no name is set.
+func (o Options) authorizer() Authorizer {
+	if strings.ToLower(o.AuthType) == IAMAuth && o.AuthName == "" {
+		return Authorizer{Name: IAMAuth, Type: o.AuthType, variables: true}
+	}
+	return Authorizer{
+		Name:             o.AuthName,
+		Type:             o.AuthType,
+		URL:              o.AuthURL,
+		ProviderARNs:     o.AuthProviderARNs,
+		LambdaAuthorizer: o.Authorizer,
+		variables:        true,
+	}
+}
+
+// authorizers the default authorizer, when set, followed by the named ones
+func (o Options) authorizers() []Authorizer {
+	var authorizers []Authorizer
+	if o.AuthType != "" {
+		authorizers = append(authorizers, o.authorizer())
+	}
+	return append(authorizers, o.Authorizers...)
+}
+
+func (o Options) isCognitoAuth() bool {
+	return o.authorizer().isCognito()
+}
+
+// hasAuthorizer tells whether the operations are secured with the default authorizer
+func (o Options) hasAuthorizer() bool {
+	return o.isCustomAuth() || o.isCognitoAuth() || o.authorizer().isIAM()
+}
+
+// validateAuth checks the authorizers, their names must be unique. The operations are secured by default, so the
+// default authorizer is required unless named authorizers are set
+func (o Options) validateAuth() []string {
+	var problems []string
+	names := map[string]bool{}
+	authorizers := o.authorizers()
+	if o.AuthType == "" && (o.AuthName != "" || len(o.Authorizers) == 0) {
+		authorizers = append([]Authorizer{o.authorizer()}, authorizers...)
+	}
+	for _, authorizer := range authorizers {
+		problems = append(problems, authorizer.Validate()...)
+		if names[authorizer.Name] {
+			problems = append(problems, fmt.Sprintf("the authorizer name %q is used more than once", authorizer.Name))
+		}
+		names[authorizer.Name] = true
+	}
+	return problems
+}
+
+// securityDefinitions returns the security definitions of all the authorizers, the iam authorizer has none
+func securityDefinitions(options Options) map[string]*swg.SecurityScheme {
+	var definitions map[string]*swg.SecurityScheme
+	for _, authorizer := range options.authorizers() {
+		if authorizer.isIAM() {
+			continue
+		}
+		if definitions == nil {
+			definitions = map[string]*swg.SecurityScheme{}
+		}
+		definitions[authorizer.Name] = authorizer.securityScheme()
+	}
+	return definitions
+}
+
+// resolveAuthorization returns the authorizer of the operation: the one of its x-authorizer extension, then of the path
+// one, then the first authorizer named by the security requirements of the operation, or of the document when it has
+// none, and the default authorizer otherwise. The operations with the x-auth-disabled extension set to true are not
+// secured, the x-amazon-apigateway-auth extension of the others is kept, they are authorized with IAM when it says so.
+func resolveAuthorization(path swg.PathItem, operation Operation, key string, security []map[string][]string, options Options) (authorization, error) {
+	if isAuthDisabled(operation.Operation) {
+		return authorization{}, nil
+	}
+	if auth, ok := operation.Extensions[IAMAuthExtension].(map[string]interface{}); ok {
+		authType, _ := auth["type"].(string)
+		return authorization{IAM: strings.EqualFold(authType, iamAuthType)}, nil
+	}
+	requirements := operation.Security
+	if requirements == nil {
+		requirements = security
+	}
+
+	authorizers := map[string]Authorizer{}
+	for _, authorizer := range options.authorizers() {
+		authorizers[authorizer.Name] = authorizer
+	}
+	for _, extensions := range []swg.Extensions{operation.Extensions, path.Extensions} {
+		if name, ok := extensions.GetString(AuthorizerExtension); ok {
+			authorizer, defined := authorizers[name]
+			if !defined {
+				return authorization{}, fmt.Errorf("%s %s selects the undefined authorizer %q", operation.Method, key, name)
+			}
+			return authorizer.authorization(requirements), nil
+		}
+	}
+	for _, requirement := range requirements {
+		for name := range requirement {
+			if authorizer, defined := authorizers[name]; defined {
+				return authorizer.authorization([]map[string][]string{requirement}), nil
+			}
+		}
+	}
+	authorizer := options.authorizer()
+	if authorizer.Name == "" {
+		return authorization{}, fmt.Errorf("%s %s is secured but no authorizer name (%s) is set", operation.Method, key, AuthName)
+	}
+	return authorizer.authorization(requirements), nil
+}
+
+// authorization secures with the authorizer, the cognito authorizer requires the oauth scopes of the requirements
+func (a Authorizer) authorization(requirements []map[string][]string) authorization {
+	if a.isIAM() {
+		return authorization{IAM: true}
+	}
+	if !a.isCognito() {
+		return authorization{Authorizer: a.Name}
+	}
+	return authorization{Authorizer: a.Name, Scopes: requirementScopes(requirements)}
+}
+
+// secure replaces the security requirements of the vanilla document with the authorizer of the operation
+func secure(op *swg.Operation, auth authorization) {
+	if auth.IAM {
+		op.Security = nil
+		op.AddExtension(IAMAuthExtension, map[string]string{"type": iamAuthType})
+		return
+	}
+	// an empty list rather than null, as the swagger specification requires
+	op.Security = []map[string][]string{{auth.Authorizer: append([]string{}, auth.Scopes...)}}
+}
+
+// isAuthDisabled tells whether the x-auth-disabled extension of the operation is set to true
+func isAuthDisabled(op *swg.Operation) bool {
+	value, ok := op.Extensions.GetString(AuthDisabledExtension)
+	if !ok {
+		return false
+	}
+	disabled, err := strconv.ParseBool(value)
+	return err == nil && disabled
+}
+
+// requirementScopes returns the scopes of all the security requirements
+func requirementScopes(requirements []map[string][]string) []string {
+	unique := map[string]bool{}
+	for _, requirement := range requirements {
+		for _, scopes := range requirement {
+			for _, scope := range scopes {
+				unique[scope] = true
+			}
+		}
+	}
+	var scopes []string
+	for scope := range unique {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
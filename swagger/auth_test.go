@@ -0,0 +1,276 @@
+package swagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldSecureTheOperationsWithTheCognitoAuthorizer(t *testing.T) {
+
+	t.Logf("Given a cognito authorizer and operations requiring oauth scopes")
+	{
+		doc := readAccountSwagger(t)
+		doc.Security = []map[string][]string{{"oauth": {"accounts/read"}}}
+		doc.Paths.Paths["/admin/accounts"].Post.Security = []map[string][]string{{"oauth": {"accounts/write", "accounts/admin"}}}
+
+		options := OptionsFromEnv()
+		options.AuthType = CognitoAuth
+		options.AuthName = "accounts-user-pool"
+		options.AuthProviderARNs = []string{"arn:aws:cognito-idp:eu-west-1:123456789012:userpool/eu-west-1_AbCdEf123"}
+
+		t.Logf("\tWhen rendering the swagger, the operations should require their scopes")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			scheme := rendered.SecurityDefinitions[options.AuthName]
+			if scheme == nil {
+				t.Fatalf("\t\tThe authorizer should be defined %v", BallotX)
+			}
+			if authType, _ := scheme.Extensions.GetString("x-amazon-apigateway-authtype"); authType == "cognito_user_pools" {
+				t.Logf("\t\tThe authorizer should be a cognito user pool authorizer %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe authorizer should be a cognito user pool authorizer, got %s %v", authType, BallotX)
+			}
+			authorizer, _ := scheme.Extensions["x-amazon-apigateway-authorizer"].(map[string]interface{})
+			if arns, _ := authorizer["providerARNs"].([]interface{}); len(arns) == 1 && arns[0] == options.AuthProviderARNs[0] {
+				t.Logf("\t\tThe authorizer should validate the tokens of the user pool %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe authorizer should validate the tokens of the user pool, got %v %v", authorizer, BallotX)
+			}
+			if len(rendered.Security) == 0 {
+				t.Logf("\t\tThe vanilla document security should be removed %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe vanilla document security should be removed, got %v %v", rendered.Security, BallotX)
+			}
+
+			expected := map[*swg.Operation][]map[string][]string{
+				rendered.Paths.Paths["/admin/accounts"].Post:      {{options.AuthName: {"accounts/admin", "accounts/write"}}},
+				rendered.Paths.Paths["/accounts/{accountId}"].Get: {{options.AuthName: {"accounts/read"}}},
+			}
+			for op, security := range expected {
+				if reflect.DeepEqual(op.Security, security) {
+					t.Logf("\t\tThe operation %s should be secured with %v %v", op.ID, security, CheckMark)
+				} else {
+					t.Errorf("\t\tThe operation %s should be secured with %v, got %v %v", op.ID, security, op.Security, BallotX)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given a cognito authorizer without user pools")
+	{
+		options := Options{APIGatewayName: "account-service", AuthType: CognitoAuth, AuthName: "accounts-user-pool",
+			AuthProviderARNs: []string{"arn:aws:iam::123456789012:role/pool"}}
+
+		t.Logf("\tWhen validating the options, the user pool arn should be rejected")
+		{
+			if problems := options.Validate(); len(problems) == 1 {
+				t.Logf("\t\tThe invalid arn should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe invalid arn should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given no authorizer at all")
+	{
+		options := Options{APIGatewayName: "account-service"}
+
+		t.Logf("\tWhen validating the options, the missing type and name of the default authorizer should be reported")
+		{
+			problems := strings.Join(options.Validate(), "\n")
+			for _, key := range []string{"(--auth-type or " + AuthType + ")", "(--auth-name or " + AuthName + ")"} {
+				if strings.Contains(problems, key) {
+					t.Logf("\t\tThe missing %s should be reported %v", key, CheckMark)
+				} else {
+					t.Errorf("\t\tThe missing %s should be reported, got %v %v", key, problems, BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldDropTheSecurityOfTheOperationsWithAuthDisabled(t *testing.T) {
+
+	t.Logf("Given an operation with auth disabled and its own security requirement")
+	{
+		doc := readAccountSwagger(t)
+		doc.Paths.Paths["/accounts/{accountId}/status"].Get.AddExtension(AuthDisabledExtension, "true")
+		doc.Paths.Paths["/accounts/{accountId}/status"].Get.Security = []map[string][]string{{"JWT": {}}}
+
+		t.Logf("\tWhen rendering the swagger, the operation should have no security requirement")
+		{
+			data, err := NewSwaggerClientWithOptions("account-service", OptionsFromEnv()).RenderSwagger(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			if op := rendered.Paths.Paths["/accounts/{accountId}/status"].Get; op.Security == nil {
+				t.Logf("\t\tThe security requirement should be dropped %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe security requirement should be dropped, got %v %v", op.Security, BallotX)
+			}
+			if _, ok := rendered.SecurityDefinitions["JWT"]; !ok {
+				t.Logf("\t\tThe JWT scheme should not be defined %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe JWT scheme should not be defined %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldAddTheRequestLambdaAuthorizer(t *testing.T) {
+
+	t.Logf("Given a request authorizer keyed on an api key and a tenant header")
+	{
+		options := OptionsFromEnv()
+		options.AuthType = CustomAuth
+		options.AuthName = "tenant-authorizer"
+		options.AuthURL = "arn:aws:apigateway:eu-west-1:lambda:path/2015-03-31/functions/arn:aws:lambda:eu-west-1:123456789012:function:tenant/invocations"
+		options.Authorizer = LambdaAuthorizer{
+			Type:            RequestAuthorizer,
+			IdentitySources: []string{"method.request.header.X-Api-Key", "method.request.header.X-Tenant-Id"},
+			ResultTTL:       300,
+			Credentials:     "arn:aws:iam::123456789012:role/authorizer",
+		}
+
+		t.Logf("\tWhen rendering the swagger, the authorizer should be invoked with the identity sources")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(readAccountSwagger(t))
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			scheme := rendered.SecurityDefinitions[options.AuthName]
+			if scheme == nil {
+				t.Fatalf("\t\tThe authorizer should be defined %v", BallotX)
+			}
+			if scheme.Name == "X-Api-Key" {
+				t.Logf("\t\tThe scheme should name the first header %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe scheme should name the first header, got %s %v", scheme.Name, BallotX)
+			}
+			authorizer, _ := scheme.Extensions["x-amazon-apigateway-authorizer"].(map[string]interface{})
+			expected := map[string]interface{}{
+				"type":                         "request",
+				"identitySource":               "method.request.header.X-Api-Key,method.request.header.X-Tenant-Id",
+				"authorizerResultTtlInSeconds": float64(300),
+				"authorizerCredentials":        "arn:aws:iam::123456789012:role/authorizer",
+			}
+			for name, value := range expected {
+				if authorizer[name] == value {
+					t.Logf("\t\t%s should be %v %v", name, value, CheckMark)
+				} else {
+					t.Errorf("\t\t%s should be %v, got %v %v", name, value, authorizer[name], BallotX)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given invalid lambda authorizer settings")
+	{
+		authorizers := map[string]LambdaAuthorizer{
+			"request without sources":      {Type: RequestAuthorizer},
+			"token reading a query string": {IdentitySources: []string{"method.request.querystring.token"}},
+			"ttl above an hour":            {ResultTTL: 3601},
+			"credentials not a role":       {Credentials: "arn:aws:lambda:eu-west-1:123456789012:function:tenant"},
+			"unknown identity source":      {Type: RequestAuthorizer, IdentitySources: []string{"header.X-Api-Key"}},
+		}
+
+		t.Logf("\tWhen validating the settings, each should be rejected")
+		{
+			for name, authorizer := range authorizers {
+				if problems := authorizer.Validate(); len(problems) > 0 {
+					t.Logf("\t\tThe %s should be rejected %v", name, CheckMark)
+				} else {
+					t.Errorf("\t\tThe %s should be rejected %v", name, BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldSecureEachOperationWithItsAuthorizer(t *testing.T) {
+
+	t.Logf("Given a default lambda authorizer, a partner and an admin authorizer")
+	{
+		doc := readAccountSwagger(t)
+		doc.Paths.Paths["/admin/accounts"].Post.AddExtension(AuthorizerExtension, "admin")
+		doc.Paths.Paths["/organisations/{orgId}"].Get.Security = []map[string][]string{{"partner": {"organisations/read"}}}
+		doc.Paths.Paths["/accounts/{accountId}/status"].Get.AddExtension(AuthDisabledExtension, "true")
+
+		options := OptionsFromEnv()
+		options.Authorizers = []Authorizer{
+			{Name: "admin", Type: CognitoAuth, ProviderARNs: []string{"arn:aws:cognito-idp:eu-west-1:123456789012:userpool/eu-west-1_Admin"}},
+			{Name: "partner", Type: CognitoAuth, ProviderARNs: []string{"arn:aws:cognito-idp:eu-west-1:123456789012:userpool/eu-west-1_Partner"}},
+		}
+
+		t.Logf("\tWhen rendering the swagger, each operation should be secured with its authorizer")
+		{
+			data, report, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			if len(rendered.SecurityDefinitions) == 3 {
+				t.Logf("\t\tAll the authorizers should be defined %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAll the authorizers should be defined, got %v %v", rendered.SecurityDefinitions, BallotX)
+			}
+
+			expected := map[*swg.Operation][]map[string][]string{
+				rendered.Paths.Paths["/admin/accounts"].Post:             {{"admin": {}}},
+				rendered.Paths.Paths["/organisations/{orgId}"].Get:       {{"partner": {"organisations/read"}}},
+				rendered.Paths.Paths["/organisations/{orgId}"].Put:       {{ExpectedAuthorizerName: {}}},
+				rendered.Paths.Paths["/accounts/{accountId}/status"].Get: nil,
+			}
+			for op, security := range expected {
+				if reflect.DeepEqual(op.Security, security) {
+					t.Logf("\t\tThe operation %s should be secured with %v %v", op.ID, security, CheckMark)
+				} else {
+					t.Errorf("\t\tThe operation %s should be secured with %v, got %v %v", op.ID, security, op.Security, BallotX)
+				}
+			}
+			if _, ok := rendered.Paths.Paths["/admin/accounts"].Post.Extensions[AuthorizerExtension]; !ok {
+				t.Logf("\t\tThe %s extension should be removed %v", AuthorizerExtension, CheckMark)
+			} else {
+				t.Errorf("\t\tThe %s extension should be removed %v", AuthorizerExtension, BallotX)
+			}
+			for _, operation := range report.Secured {
+				if operation == "GET /accounts/{accountId}/status" {
+					t.Errorf("\t\tThe operation with auth disabled should be reported unsecured %v", BallotX)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given an operation selecting an undefined authorizer")
+	{
+		doc := readAccountSwagger(t)
+		doc.Paths.Paths["/admin/accounts"].Post.AddExtension(AuthorizerExtension, "unknown")
+
+		t.Logf("\tWhen rendering the swagger, an error should be returned")
+		{
+			if _, _, err := NewSwaggerClientWithOptions("account-service", OptionsFromEnv()).RenderSwaggerWithReport(doc); err != nil {
+				t.Logf("\t\tThe undefined authorizer should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe undefined authorizer should be reported %v", BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package swagger
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CorsAllowOrigins     = "CORS_ALLOW_ORIGINS"
+	CorsAllowMethods     = "CORS_ALLOW_METHODS"
+	CorsAllowHeaders     = "CORS_ALLOW_HEADERS"
+	CorsExposeHeaders    = "CORS_EXPOSE_HEADERS"
+	CorsMaxAge           = "CORS_MAX_AGE"
+	CorsAllowCredentials = "CORS_ALLOW_CREDENTIALS"
+
+	// CorsExtension the path extension overriding the cors settings of the API
+	CorsExtension = "x-apigw-pub-cors"
+
+	allowMethodsHeader     = "Access-Control-Allow-Methods"
+	allowHeadersHeader     = "Access-Control-Allow-Headers"
+	exposeHeadersHeader    = "Access-Control-Expose-Headers"
+	maxAgeHeader           = "Access-Control-Max-Age"
+	allowCredentialsHeader = "Access-Control-Allow-Credentials"
+	anyOrigin              = "*"
+)
+
+// defaultAllowHeaders the request headers browsers are allowed to send when none are configured, the custom headers are
+// always allowed
+var defaultAllowHeaders = []string{"Content-Type", "X-Amz-Date", "Authorization", "X-Api-Key", "X-Amz-Security-Token", "DNT",
+	"Origin", "Referer", "Sec-Fetch-Mode", "User-Agent", "Access-Control-Request-Headers", "Access-Control-Request-Method"}
+
+// Cors the cors settings of the API, used when cors is enabled
+type Cors struct {
+	// AllowOrigins the origins allowed to call the API, any origin by default
+	AllowOrigins []string `json:"allowOrigins,omitempty" yaml:"allowOrigins"`
+	// AllowMethods the methods allowed, the ones of the path by default
+	AllowMethods     []string `json:"allowMethods,omitempty" yaml:"allowMethods"`
+	AllowHeaders     []string `json:"allowHeaders,omitempty" yaml:"allowHeaders"`
+	ExposeHeaders    []string `json:"exposeHeaders,omitempty" yaml:"exposeHeaders"`
+	MaxAge           int      `json:"maxAge,omitempty" yaml:"maxAge"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty" yaml:"allowCredentials"`
+}
+
+// corsOverride the `x-apigw-pub-cors` extension of a path, only the fields set override the settings of the API
+type corsOverride struct {
+	Enabled          *bool    `json:"enabled"`
+	AllowOrigins     []string `json:"allowOrigins"`
+	AllowMethods     []string `json:"allowMethods"`
+	AllowHeaders     []string `json:"allowHeaders"`
+	ExposeHeaders    []string `json:"exposeHeaders"`
+	MaxAge           *int     `json:"maxAge"`
+	AllowCredentials *bool    `json:"allowCredentials"`
+}
+
+// ApplyEnv overrides the settings with the environment variables which are set and returns the invalid ones
+func (c *Cors) ApplyEnv() []string {
+	var problems []string
+	for name, list := range map[string]*[]string{
+		CorsAllowOrigins:  &c.AllowOrigins,
+		CorsAllowMethods:  &c.AllowMethods,
+		CorsAllowHeaders:  &c.AllowHeaders,
+		CorsExposeHeaders: &c.ExposeHeaders,
+	} {
+		if value, ok := os.LookupEnv(name); ok {
+			*list = SplitList(value)
+		}
+	}
+	if value, ok := os.LookupEnv(CorsMaxAge); ok {
+		maxAge, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid cors max age (%s) %q, expected a number of seconds", CorsMaxAge, value))
+		}
+		c.MaxAge = maxAge
+	}
+	if value, ok := os.LookupEnv(CorsAllowCredentials); ok {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid cors allow credentials (%s) %q, expected true or false", CorsAllowCredentials, value))
+		}
+		c.AllowCredentials = allow
+	}
+	return problems
+}
+
+// Validate returns the problems of the settings
+func (c Cors) Validate() []string {
+	var problems []string
+	if c.MaxAge < 0 {
+		problems = append(problems, fmt.Sprintf("the cors max age %d must not be negative", c.MaxAge))
+	}
+	for _, method := range c.AllowMethods {
+		if !contains(corsMethods, strings.ToUpper(method)) {
+			problems = append(problems, fmt.Sprintf("unsupported cors method %q, expected one of %s", method, strings.Join(corsMethods, ", ")))
+		}
+	}
+	if c.AllowCredentials && (len(c.AllowOrigins) == 0 || contains(c.AllowOrigins, anyOrigin)) {
+		problems = append(problems, "the cors credentials cannot be allowed for any origin, the allowed origins must be listed")
+	}
+	return problems
+}
+
+var corsMethods = []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodOptions, http.MethodHead, http.MethodPatch}
+
+// resolveCors returns the options of the path, with the cors settings of its `x-apigw-pub-cors` extension applied
+func resolveCors(path swg.PathItem, key string, options Options) (Options, error) {
+	value, ok := path.Extensions[CorsExtension]
+	if !ok {
+		return options, nil
+	}
+	var override corsOverride
+	if err := decodeExtension(value, &override); err != nil {
+		return options, fmt.Errorf("invalid %s extension on %s: %s", CorsExtension, key, err)
+	}
+
+	if override.Enabled != nil {
+		options.CorsEnabled = *override.Enabled
+	}
+	cors := options.Cors
+	for _, list := range []struct{ value, target *[]string }{
+		{&override.AllowOrigins, &cors.AllowOrigins},
+		{&override.AllowMethods, &cors.AllowMethods},
+		{&override.AllowHeaders, &cors.AllowHeaders},
+		{&override.ExposeHeaders, &cors.ExposeHeaders},
+	} {
+		if *list.value != nil {
+			*list.target = *list.value
+		}
+	}
+	if override.MaxAge != nil {
+		cors.MaxAge = *override.MaxAge
+	}
+	if override.AllowCredentials != nil {
+		cors.AllowCredentials = *override.AllowCredentials
+	}
+	if problems := cors.Validate(); len(problems) > 0 {
+		return options, fmt.Errorf("invalid %s extension on %s: %s", CorsExtension, key, strings.Join(problems, "; "))
+	}
+	options.Cors = cors
+	return options, nil
+}
+
+// allowMethods returns the configured methods, or the ones of the path
+func (c Cors) allowMethods(path swg.PathItem) []string {
+	if len(c.AllowMethods) > 0 {
+		return upper(c.AllowMethods)
+	}
+	methods := []string{}
+	for _, operation := range Operations(path) {
+		if operation.Method == AnyMethod {
+			return corsMethods
+		}
+		if operation.Method != http.MethodOptions {
+			methods = append(methods, operation.Method)
+		}
+	}
+	return append(methods, http.MethodOptions)
+}
+
+// allowHeaders returns the configured headers, or the default ones, along with the custom headers
+func (c Cors) allowHeaders(customHeaders []string) []string {
+	headers := append([]string{}, c.AllowHeaders...)
+	if len(headers) == 0 {
+		headers = append(headers, defaultAllowHeaders...)
+	}
+	for _, header := range customHeaders {
+		if !contains(headers, header) {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// allowOrigin returns the static value of the Access-Control-Allow-Origin header, the first allowed origin when several
+// are allowed
+func (c Cors) allowOrigin() string {
+	if len(c.AllowOrigins) == 0 {
+		return anyOrigin
+	}
+	return c.AllowOrigins[0]
+}
+
+func (c Cors) severalOrigins() bool {
+	return len(c.AllowOrigins) > 1
+}
+
+// corsResponseParameters returns the cors headers of the operation responses, only the origin is set when cors is
+// disabled. With several allowed origins the origin checked by the backend is passed through.
+func corsResponseParameters(options Options) map[string]string {
+	parameters := map[string]string{}
+	if !options.CorsEnabled {
+		parameters[allowOriginHeader] = quote(anyOrigin)
+		return parameters
+	}
+
+	cors := options.Cors
+	parameters[allowOriginHeader] = quote(cors.allowOrigin())
+	if cors.severalOrigins() {
+		parameters[allowOriginHeader] = fmt.Sprintf("integration.response.header.%s", allowOriginHeader)
+	}
+	if len(cors.ExposeHeaders) > 0 {
+		parameters[exposeHeadersHeader] = quote(strings.Join(cors.ExposeHeaders, ","))
+	}
+	if cors.AllowCredentials {
+		parameters[allowCredentialsHeader] = quote("true")
+	}
+	return parameters
+}
+
+// addOperationCORSHeaders declares the cors headers in all the responses of the operation, the passed through headers
+// are kept
+func addOperationCORSHeaders(op *swg.Operation, options Options) {
+	if op.Responses == nil {
+		return
+	}
+	for key, response := range op.OperationProps.Responses.ResponsesProps.StatusCodeResponses {
+		headers := map[string]swg.Header{}
+		for name, header := range response.Headers {
+			headers[name] = header
+		}
+		for name := range corsResponseParameters(options) {
+			headers[name] = swg.Header{SimpleSchema: swg.SimpleSchema{Type: "string"}}
+		}
+		response.Headers = headers
+		op.OperationProps.Responses.ResponsesProps.StatusCodeResponses[key] = response
+	}
+}
+
+// addOptionsCORSSupport makes the OPTIONS operation answer the preflight requests with a mock integration. With several
+// allowed origins the origin of the request is returned when it is one of them.
+func addOptionsCORSSupport(op *swg.Operation, key string, methods []string, options Options) {
+	log.WithFields(log.Fields{"URL": key}).Info("Adding CORS Support to endpoint")
+
+	cors := options.Cors
+	parameters := map[string]string{
+		allowOriginHeader:  quote(cors.allowOrigin()),
+		allowMethodsHeader: quote(strings.Join(methods, ",")),
+		allowHeadersHeader: quote(strings.Join(cors.allowHeaders(options.CustomHeaders), ",")),
+	}
+	if len(cors.ExposeHeaders) > 0 {
+		parameters[exposeHeadersHeader] = quote(strings.Join(cors.ExposeHeaders, ","))
+	}
+	if cors.MaxAge > 0 {
+		parameters[maxAgeHeader] = quote(strconv.Itoa(cors.MaxAge))
+	}
+	if cors.AllowCredentials {
+		parameters[allowCredentialsHeader] = quote("true")
+	}
+
+	headers := map[string]swg.Header{}
+	responseParameters := map[string]string{}
+	for name, value := range parameters {
+		headers[name] = swg.Header{SimpleSchema: swg.SimpleSchema{Type: "string"}}
+		responseParameters[fmt.Sprintf("method.response.header.%s", name)] = value
+	}
+
+	op.OperationProps = swg.OperationProps{
+		Summary:     "CORS Support",
+		Description: "Enable CORS Support by returning correct headers",
+		Consumes:    []string{"text/json", "application/json"},
+		Produces:    []string{"text/json", "application/json"},
+		Responses: &swg.Responses{
+			ResponsesProps: swg.ResponsesProps{
+				StatusCodeResponses: map[int]swg.Response{
+					200: {ResponseProps: swg.ResponseProps{Headers: headers}},
+				},
+			},
+		},
+	}
+
+	response := map[string]interface{}{
+		"statusCode":         "200",
+		"responseParameters": responseParameters,
+	}
+	if cors.severalOrigins() {
+		response["responseTemplates"] = map[string]string{"application/json": originTemplate(cors.AllowOrigins)}
+	}
+	extension := model.AWSAPIGatewayIntegration{
+		IntegrationType:     "mock",
+		PassthroughBehavior: "when_no_match",
+		HTTPMethod:          http.MethodOptions,
+		RequestTemplates:    map[string]string{"application/json": "{\"statusCode\": 200}"},
+		Responses:           map[string]map[string]interface{}{"default": response},
+	}
+	op.VendorExtensible.AddExtension("x-amazon-apigateway-integration", extension)
+}
+
+// originTemplate returns the origin of the request when it is allowed
+func originTemplate(origins []string) string {
+	var quoted []string
+	for _, origin := range origins {
+		quoted = append(quoted, fmt.Sprintf("%q", origin))
+	}
+	return fmt.Sprintf(`#set($origin = $input.params().header.get("Origin"))
+#if([%s].contains($origin))
+#set($context.responseOverride.header.%s = $origin)
+#end`, strings.Join(quoted, ", "), allowOriginHeader)
+}
+
+func upper(values []string) []string {
+	var result []string
+	for _, value := range values {
+		result = append(result, strings.ToUpper(value))
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package swagger
+
+import (
+	"encoding/json"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldApplyTheCorsSettingsPerPath(t *testing.T) {
+
+	t.Logf("Given cors is enabled for two origins and a path disables it")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/admin/accounts"]
+		item.AddExtension(CorsExtension, map[string]interface{}{"enabled": false})
+		doc.Paths.Paths["/admin/accounts"] = item
+
+		options := OptionsFromEnv()
+		options.CorsEnabled = true
+		options.CustomHeaders = []string{"organisation-id"}
+		options.Cors = Cors{
+			AllowOrigins:     []string{"https://app.example.com", "https://admin.example.com"},
+			ExposeHeaders:    []string{"ETag"},
+			MaxAge:           600,
+			AllowCredentials: true,
+		}
+
+		t.Logf("\tWhen rendering the swagger, the preflight responses should follow the settings")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			path := rendered.Paths.Paths["/accounts/{accountId}"]
+			if path.Options == nil {
+				t.Fatalf("\t\tThe preflight operation should be added %v", BallotX)
+			}
+			preflight := integrationOf(path.Options).Responses["default"]
+			parameters, _ := preflight["responseParameters"].(map[string]interface{})
+			expected := map[string]string{
+				"method.response.header.Access-Control-Allow-Methods":     "'GET,PUT,PATCH,OPTIONS'",
+				"method.response.header.Access-Control-Allow-Origin":      "'https://app.example.com'",
+				"method.response.header.Access-Control-Max-Age":           "'600'",
+				"method.response.header.Access-Control-Allow-Credentials": "'true'",
+				"method.response.header.Access-Control-Expose-Headers":    "'ETag'",
+			}
+			for name, value := range expected {
+				if parameters[name] == value {
+					t.Logf("\t\t%s should be %s %v", name, value, CheckMark)
+				} else {
+					t.Errorf("\t\t%s should be %s, got %v %v", name, value, parameters[name], BallotX)
+				}
+			}
+			if preflight["responseTemplates"] != nil {
+				t.Logf("\t\tThe preflight response should return the allowed origin of the request %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe preflight response should return the allowed origin of the request %v", BallotX)
+			}
+
+			get := integrationOf(path.Get).Responses["200"]["responseParameters"].(map[string]interface{})
+			if get["method.response.header.Access-Control-Allow-Origin"] == "integration.response.header.Access-Control-Allow-Origin" {
+				t.Logf("\t\tThe origin checked by the backend should be passed through %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe origin checked by the backend should be passed through, got %v %v", get, BallotX)
+			}
+
+			if rendered.Paths.Paths["/admin/accounts"].Options == nil {
+				t.Logf("\t\tThe path disabling cors should have no preflight operation %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe path disabling cors should have no preflight operation %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldOnlyAddThePreflightToThePublishedPaths(t *testing.T) {
+
+	t.Logf("Given cors is enabled and a path is not published")
+	{
+		doc := readAccountSwagger(t)
+		doc.Paths.Paths["/admin/accounts"].Post.AddExtension("x-publish", "false")
+
+		options := OptionsFromEnv()
+		options.CorsEnabled = true
+
+		t.Logf("\tWhen rendering the swagger, the unpublished path should not come back with a preflight operation")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			if _, ok := rendered.Paths.Paths["/admin/accounts"]; !ok {
+				t.Logf("\t\tThe unpublished path should be left out %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe unpublished path should be left out %v", BallotX)
+			}
+			if rendered.Paths.Paths["/accounts/{accountId}"].Options != nil {
+				t.Logf("\t\tThe published paths should have a preflight operation %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe published paths should have a preflight operation %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestCorsValidate_ShouldRejectCredentialsForAnyOrigin(t *testing.T) {
+
+	t.Logf("Given the credentials are allowed without listing the origins")
+	{
+		cors := Cors{AllowCredentials: true, AllowMethods: []string{"GET", "TRACE"}}
+
+		t.Logf("\tWhen validating the settings, the credentials and the method should be reported")
+		{
+			if problems := cors.Validate(); len(problems) == 2 {
+				t.Logf("\t\tThe problems should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe problems should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package swagger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	EndpointScheme = "ENDPOINT_SCHEME"
+	EndpointPort   = "ENDPOINT_PORT"
+
+	httpScheme      = "http"
+	httpsScheme     = "https"
+	schemeSeparator = "://"
+)
+
+// backendURL returns the base url of the backend: the endpoint url, or the swagger host and basePath, prefixed with the
+// scheme and given the port when they are not part of it. Stage variables, exp: `https://${stageVariables.backendHost}/api`,
+// are kept as is so one import can serve several stages. The host and path are replaced with a reference to the
+// endpointUrl stage variable when the stage variables are enabled.
+func backendURL(doc swg.Swagger, options Options) string {
+	url := options.EndpointURL
+	if url == "" {
+		url = fmt.Sprintf("%s%s", doc.Host, doc.BasePath)
+	}
+
+	scheme := options.scheme(doc)
+	if i := strings.Index(url, schemeSeparator); i >= 0 {
+		scheme, url = url[:i], url[i+len(schemeSeparator):]
+	}
+
+	host, path := url, ""
+	if i := strings.Index(url, "/"); i >= 0 {
+		host, path = url[:i], url[i:]
+	}
+	if options.EndpointPort != "" && !strings.Contains(host, ":") {
+		host = fmt.Sprintf("%s:%s", host, options.EndpointPort)
+	}
+	if options.StageVariablesEnabled {
+		return fmt.Sprintf("%s%s%s", strings.ToLower(scheme), schemeSeparator, options.stageVariableReference(EndpointURLVariable))
+	}
+	return fmt.Sprintf("%s%s%s%s", strings.ToLower(scheme), schemeSeparator, host, strings.TrimRight(path, "/"))
+}
+
+// scheme returns the configured scheme, or https when it is the only scheme of the swagger document, http otherwise
+func (o Options) scheme(doc swg.Swagger) string {
+	if o.EndpointScheme != "" {
+		return o.EndpointScheme
+	}
+	for _, scheme := range doc.Schemes {
+		if strings.ToLower(scheme) == httpScheme {
+			return httpScheme
+		}
+	}
+	for _, scheme := range doc.Schemes {
+		if strings.ToLower(scheme) == httpsScheme {
+			return httpsScheme
+		}
+	}
+	return httpScheme
+}
+
+// validateEndpoint checks the scheme and the port of the backend
+func (o Options) validateEndpoint() []string {
+	var problems []string
+	schemes := []string{strings.ToLower(o.EndpointScheme)}
+	if i := strings.Index(o.EndpointURL, schemeSeparator); i >= 0 {
+		schemes = append(schemes, strings.ToLower(o.EndpointURL[:i]))
+	}
+	for _, scheme := range schemes {
+		if scheme != "" && scheme != httpScheme && scheme != httpsScheme {
+			problems = append(problems, fmt.Sprintf("unsupported endpoint scheme %q, expected %s or %s", scheme, httpScheme, httpsScheme))
+		}
+	}
+	if o.EndpointPort != "" {
+		if port, err := strconv.Atoi(o.EndpointPort); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("invalid endpoint port (%s) %q", EndpointPort, o.EndpointPort))
+		}
+	}
+	return problems
+}
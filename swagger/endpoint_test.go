@@ -0,0 +1,96 @@
+package swagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestBackendURL_ShouldHonourTheSchemeAndPort(t *testing.T) {
+
+	t.Logf("Given swagger documents and endpoint urls with and without scheme and port")
+	{
+		doc := func(host, basePath string, schemes ...string) swg.Swagger {
+			return swg.Swagger{SwaggerProps: swg.SwaggerProps{Host: host, BasePath: basePath, Schemes: schemes}}
+		}
+		cases := []struct {
+			name     string
+			doc      swg.Swagger
+			options  Options
+			expected string
+		}{
+			{"swagger host", doc("internal-api.dev.co.uk", "/account-service/"), Options{}, "http://internal-api.dev.co.uk/account-service"},
+			{"https only swagger", doc("internal-api.dev.co.uk:8443", "/account-service", "https"), Options{}, "https://internal-api.dev.co.uk:8443/account-service"},
+			{"http and https swagger", doc("internal-api.dev.co.uk", "/", "https", "http"), Options{}, "http://internal-api.dev.co.uk"},
+			{"configured scheme and port", doc("internal-api.dev.co.uk", "/account-service"), Options{EndpointScheme: "https", EndpointPort: "8443"}, "https://internal-api.dev.co.uk:8443/account-service"},
+			{"endpoint url with scheme", doc("ignored", "/", "http"), Options{EndpointURL: "https://account.internal:9443/api/"}, "https://account.internal:9443/api"},
+			{"stage variables", doc("ignored", "/"), Options{EndpointURL: "https://${stageVariables.backendHost}/api"}, "https://${stageVariables.backendHost}/api"},
+		}
+
+		t.Logf("\tWhen building the backend url, the scheme and port should be kept or added")
+		{
+			for _, c := range cases {
+				if url := backendURL(c.doc, c.options); url == c.expected {
+					t.Logf("\t\t%s: %s %v", c.name, url, CheckMark)
+				} else {
+					t.Errorf("\t\t%s: expected %s, got %s %v", c.name, c.expected, url, BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestOptionsValidate_ShouldReportInvalidSchemeAndPort(t *testing.T) {
+
+	t.Logf("Given options with an ftp endpoint url and an invalid port")
+	{
+		options := Options{APIGatewayName: "api-gw-dev", AuthType: IAMAuth, EndpointURL: "ftp://account.internal", EndpointPort: "99999"}
+
+		t.Logf("\tWhen validating the options, both problems should be reported")
+		{
+			if problems := options.Validate(); len(problems) == 2 {
+				t.Logf("\t\tThe scheme and port problems should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe scheme and port problems should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldReferenceTheStageVariables(t *testing.T) {
+
+	t.Logf("Given the stage variables are enabled for a service behind a vpc link")
+	{
+		options := OptionsFromEnv()
+		options.EndpointURL = "https://account.internal/account-service"
+		options.ConnectionType = VPCLinkConnectionType
+		options.VPCLinkID = "abc123"
+		options.StageVariablesEnabled = true
+
+		t.Logf("\tWhen rendering the swagger, the integrations should reference the stage variables")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(readAccountSwagger(t))
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			integration := integrationOf(rendered.Paths.Paths["/accounts/{accountId}"].Get)
+			if integration.URI == "https://${stageVariables.endpointUrl}/accounts/{accountId}" && integration.ConnectionID == "${stageVariables.vpcLinkId}" {
+				t.Logf("\t\tThe uri and connection id should reference the stage variables %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe uri and connection id should reference the stage variables, got %+v %v", integration, BallotX)
+			}
+
+			expected := map[string]string{EndpointURLVariable: "account.internal/account-service", VPCLinkIDVariable: "abc123"}
+			if variables := options.StageVariables(); reflect.DeepEqual(variables, expected) {
+				t.Logf("\t\tThe stage variables should be set to the configured values %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe stage variables should be set to the configured values, got %v %v", variables, BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package swagger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	SwaggerTimeout      = "SWAGGER_TIMEOUT"
+	SwaggerRetries      = "SWAGGER_RETRIES"
+	SwaggerRetryBackoff = "SWAGGER_RETRY_BACKOFF"
+	SwaggerBearerToken  = "SWAGGER_BEARER_TOKEN"
+	SwaggerBasicAuth    = "SWAGGER_BASIC_AUTH"
+	SwaggerHeaders      = "SWAGGER_HEADERS"
+	SwaggerCABundle     = "SWAGGER_CA_BUNDLE"
+	SwaggerClientCert   = "SWAGGER_CLIENT_CERT"
+	SwaggerClientKey    = "SWAGGER_CLIENT_KEY"
+
+	DefaultTimeout      = 30 * time.Second
+	DefaultRetries      = 3
+	DefaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// FetchOptions the settings used to fetch the swagger document from a remote service
+type FetchOptions struct {
+	// Timeout of each attempt, DefaultTimeout when not set
+	Timeout time.Duration
+	// Retries the number of retries on connection errors and 5xx responses
+	Retries int
+	// RetryBackoff the delay before the first retry, doubled on every retry
+	RetryBackoff time.Duration
+	BearerToken  string
+	Username     string
+	Password     string
+	Headers      map[string]string
+	// CABundle the pem file of the certificate authorities trusted on top of the system ones
+	CABundle string
+	// ClientCert and ClientKey the pem files of the client certificate presented for mTLS
+	ClientCert string
+	ClientKey  string
+}
+
+// FetchOptionsFromEnv - Function
+// Reads the fetch options from the environment variables, invalid values are reported as problems
+func FetchOptionsFromEnv() (FetchOptions, []string) {
+	options := FetchOptions{
+		Timeout:      DefaultTimeout,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+	problems := options.ApplyEnv()
+	return options, problems
+}
+
+// ApplyEnv overrides the options with the environment variables which are set, invalid values are reported as problems
+func (o *FetchOptions) ApplyEnv() []string {
+	var problems []string
+	if value, ok := os.LookupEnv(SwaggerTimeout); ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid swagger fetch timeout (%s) %q, expected a duration such as 30s", SwaggerTimeout, value))
+		}
+		o.Timeout = timeout
+	}
+	if value, ok := os.LookupEnv(SwaggerRetries); ok {
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid swagger fetch retries (%s) %q, expected a number", SwaggerRetries, value))
+		}
+		o.Retries = retries
+	}
+	if value, ok := os.LookupEnv(SwaggerRetryBackoff); ok {
+		backoff, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid swagger fetch retry backoff (%s) %q, expected a duration such as 1s", SwaggerRetryBackoff, value))
+		}
+		o.RetryBackoff = backoff
+	}
+	if value, ok := os.LookupEnv(SwaggerBasicAuth); ok {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 {
+			problems = append(problems, fmt.Sprintf("invalid swagger basic auth (%s), expected username:password", SwaggerBasicAuth))
+		} else {
+			o.Username, o.Password = parts[0], parts[1]
+		}
+	}
+	if value, ok := os.LookupEnv(SwaggerHeaders); ok {
+		headers, err := ParseHeaders(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid swagger headers (%s): %s", SwaggerHeaders, err))
+		}
+		o.Headers = headers
+	}
+	lookupEnv(SwaggerBearerToken, &o.BearerToken)
+	lookupEnv(SwaggerCABundle, &o.CABundle)
+	lookupEnv(SwaggerClientCert, &o.ClientCert)
+	lookupEnv(SwaggerClientKey, &o.ClientKey)
+	return problems
+}
+
+// Validate returns all the problems found in the fetch options
+func (o FetchOptions) Validate() []string {
+	var problems []string
+	if o.Timeout < 0 {
+		problems = append(problems, fmt.Sprintf("the swagger fetch timeout (%s) must not be negative", SwaggerTimeout))
+	}
+	if o.Retries < 0 {
+		problems = append(problems, fmt.Sprintf("the swagger fetch retries (%s) must not be negative", SwaggerRetries))
+	}
+	if o.RetryBackoff < 0 {
+		problems = append(problems, fmt.Sprintf("the swagger fetch retry backoff (%s) must not be negative", SwaggerRetryBackoff))
+	}
+	if o.BearerToken != "" && o.Username != "" {
+		problems = append(problems, fmt.Sprintf("the swagger bearer token (%s) and basic auth (%s) are mutually exclusive", SwaggerBearerToken, SwaggerBasicAuth))
+	}
+	if (o.ClientCert == "") != (o.ClientKey == "") {
+		problems = append(problems, fmt.Sprintf("the client certificate (%s) and key (%s) must be set together", SwaggerClientCert, SwaggerClientKey))
+	}
+	return problems
+}
+
+// ParseHeaders parses a comma separated list of `Name: value` headers
+func ParseHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, header := range SplitList(value) {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%q is not a Name: value header", header)
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers, nil
+}
+
+// httpClient builds the http client with the configured timeout and tls settings
+func (o FetchOptions) httpClient() (*http.Client, error) {
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if o.CABundle == "" && o.ClientCert == "" {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if o.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(o.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the ca bundle %s", o.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if o.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+	return client, nil
+}
+
+// authenticate adds the credentials and the custom headers to the request
+func (o FetchOptions) authenticate(req *http.Request) {
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	if o.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	}
+	if o.Username != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+}
+
+// fetchRemote fetches the swagger doc from the given url, connection errors and 5xx responses are retried with an
+// exponential backoff
+func (client SwaggerParser) fetchRemote() ([]byte, error) {
+
+	log.WithFields(log.Fields{"Swagger URL": client.swaggerUrl}).Info("Fetching vanilla swagger from the given swagger url")
+
+	options := client.options.Fetch
+	httpClient, err := options.httpClient()
+	if err != nil {
+		log.Errorf("Failed to configure the http client: %s", err)
+		return nil, err
+	}
+
+	backoff := options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		body, retry, err := client.get(httpClient)
+		if err == nil {
+			return body, nil
+		}
+		if !retry || attempt >= options.Retries {
+			log.Errorf("Failed to fetch swagger doc from %s", client.swaggerUrl)
+			return nil, err
+		}
+
+		log.WithFields(log.Fields{"error": err, "retry": attempt + 1, "backoff": backoff}).Warn("Failed to fetch swagger doc, retrying")
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// get runs a single attempt, it tells whether the error is worth a retry
+func (client SwaggerParser) get(httpClient *http.Client) ([]byte, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, client.swaggerUrl, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	client.options.Fetch.authenticate(req)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Errorf("Error when getting Swagger docs: %s", err)
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Got error from the server with http code %d", resp.StatusCode)
+		return nil, resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("Got error from the server with http code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return body, err != nil, err
+}
+
+func lookupEnv(key string, value *string) {
+	if envValue, ok := os.LookupEnv(key); ok {
+		*value = envValue
+	}
+}
@@ -0,0 +1,162 @@
+package swagger
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFetchSwagger_ShouldRetryConnectionErrorsAndServerErrors(t *testing.T) {
+
+	t.Logf("Given the swagger document is served by a flaky service")
+	{
+		swagger, _ := ioutil.ReadFile("../data/swagger.json")
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts++; attempts < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write(swagger)
+		}))
+		defer server.Close()
+
+		t.Logf("\tWhen the service fails twice with a 5xx, it should be retried")
+		{
+			options := Options{Fetch: FetchOptions{Retries: 2, RetryBackoff: time.Millisecond}}
+			doc, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger()
+			if err == nil && attempts == 3 && doc.Info.Title == "Account API" {
+				t.Logf("\t\tThe document should be fetched on the third attempt %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe document should be fetched on the third attempt, got %d attempts %v %v", attempts, err, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen the retries are exhausted, it should return the error")
+		{
+			attempts = 0
+			options := Options{Fetch: FetchOptions{Retries: 1, RetryBackoff: time.Millisecond}}
+			_, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger()
+			if err != nil && attempts == 2 {
+				t.Logf("\t\tAn error should be returned after 2 attempts %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAn error should be returned after 2 attempts, got %d attempts %v", attempts, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given the swagger document is not found")
+	{
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		t.Logf("\tWhen fetching the swagger, it should not be retried")
+		{
+			options := Options{Fetch: FetchOptions{Retries: 3, RetryBackoff: time.Millisecond}}
+			_, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger()
+			if err != nil && attempts == 1 {
+				t.Logf("\t\tAn error should be returned after a single attempt %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAn error should be returned after a single attempt, got %d attempts %v", attempts, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given the swagger service is down")
+	{
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		t.Logf("\tWhen fetching the swagger, it should return an error rather than panic")
+		{
+			options := Options{Fetch: FetchOptions{Retries: 1, RetryBackoff: time.Millisecond}}
+			if _, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger(); err != nil {
+				t.Logf("\t\tAn error should be returned %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAn error should be returned %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestFetchSwagger_ShouldSendCredentialsAndCustomHeaders(t *testing.T) {
+
+	t.Logf("Given the swagger document is served by a protected endpoint")
+	{
+		swagger, _ := ioutil.ReadFile("../data/swagger.json")
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+			w.Write(swagger)
+		}))
+		defer server.Close()
+
+		t.Logf("\tWhen fetching with a bearer token, it should be sent in the Authorization header")
+		{
+			options := Options{Fetch: FetchOptions{BearerToken: "secret", Headers: map[string]string{"X-Api-Key": "key"}}}
+			_, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger()
+			if err == nil && received.Get("Authorization") == "Bearer secret" && received.Get("X-Api-Key") == "key" {
+				t.Logf("\t\tThe token and the custom headers should be sent %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe token and the custom headers should be sent, got %v %v", received, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching with basic auth, it should be sent in the Authorization header")
+		{
+			options := Options{Fetch: FetchOptions{Username: "user", Password: "password"}}
+			_, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger()
+			if err == nil && received.Get("Authorization") == "Basic dXNlcjpwYXNzd29yZA==" {
+				t.Logf("\t\tThe basic auth credentials should be sent %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe basic auth credentials should be sent, got %v %v", received, BallotX)
+			}
+		}
+	}
+}
+
+func TestFetchSwagger_ShouldTrustTheGivenCABundle(t *testing.T) {
+
+	t.Logf("Given the swagger document is served by an internal service with a private certificate authority")
+	{
+		swagger, _ := ioutil.ReadFile("../data/swagger.json")
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(swagger)
+		}))
+		defer server.Close()
+
+		t.Logf("\tWhen fetching without the ca bundle, it should fail")
+		{
+			if _, err := NewSwaggerClientWithOptions(server.URL, Options{}).FetchSwagger(); err != nil {
+				t.Logf("\t\tThe server certificate should not be trusted %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe server certificate should not be trusted %v", BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching with the ca bundle, it should succeed")
+		{
+			dir, _ := ioutil.TempDir("", "apigw-pub")
+			defer os.RemoveAll(dir)
+			bundle := filepath.Join(dir, "ca.pem")
+			block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+			ioutil.WriteFile(bundle, pem.EncodeToMemory(block), os.ModePerm)
+
+			options := Options{Fetch: FetchOptions{CABundle: bundle}}
+			if _, err := NewSwaggerClientWithOptions(server.URL, options).FetchSwagger(); err == nil {
+				t.Logf("\t\tThe server certificate should be trusted %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe server certificate should be trusted %v %v", err, BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package swagger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// GatewayResponsesExtension the responses API Gateway returns when it rejects a request
+	GatewayResponsesExtension = "x-amazon-apigateway-gateway-responses"
+
+	Default4XX = "DEFAULT_4XX"
+	Default5XX = "DEFAULT_5XX"
+)
+
+// gatewayResponseTypes the gateway responses which can be customised
+var gatewayResponseTypes = []string{
+	"ACCESS_DENIED", "API_CONFIGURATION_ERROR", "AUTHORIZER_CONFIGURATION_ERROR", "AUTHORIZER_FAILURE", "BAD_REQUEST_BODY",
+	"BAD_REQUEST_PARAMETERS", Default4XX, Default5XX, "EXPIRED_TOKEN", "INTEGRATION_FAILURE", "INTEGRATION_TIMEOUT",
+	"INVALID_API_KEY", "INVALID_SIGNATURE", "MISSING_AUTHENTICATION_TOKEN", "QUOTA_EXCEEDED", "REQUEST_TOO_LARGE",
+	"RESOURCE_NOT_FOUND", "THROTTLED", "UNAUTHORIZED", "UNSUPPORTED_MEDIA_TYPE", "WAF_FILTERED",
+}
+
+// GatewayResponse the status code, headers and body API Gateway returns for one of the gateway response types
+type GatewayResponse struct {
+	StatusCode string `json:"statusCode,omitempty" yaml:"statusCode"`
+	// Headers the header values, literal values are quoted, exp: `'*'`, unless they reference the request, the
+	// context or the stage variables, exp: method.request.header.Origin
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers"`
+	// Templates the body per content type, exp: {"message": $context.error.messageString}
+	Templates map[string]string `json:"templates,omitempty" yaml:"templates"`
+}
+
+// addGatewayResponses adds the configured gateway responses, the cors headers are added to all of them when cors is
+// enabled so browsers can read the errors
+func addGatewayResponses(doc *swg.Swagger, options Options) {
+	responses := map[string]model.AWSAPIGatewayGatewayResponse{}
+	for responseType, response := range options.GatewayResponses {
+		responses[strings.ToUpper(responseType)] = response.render()
+	}
+	if options.CorsEnabled {
+		for _, responseType := range []string{Default4XX, Default5XX} {
+			if _, ok := responses[responseType]; !ok {
+				responses[responseType] = model.AWSAPIGatewayGatewayResponse{}
+			}
+		}
+		for responseType, response := range responses {
+			if response.ResponseParameters == nil {
+				response.ResponseParameters = map[string]string{}
+			}
+			for name, value := range gatewayCorsHeaders(options) {
+				if _, ok := response.ResponseParameters[responseHeader(name)]; !ok {
+					response.ResponseParameters[responseHeader(name)] = value
+				}
+			}
+			responses[responseType] = response
+		}
+	}
+	if len(responses) > 0 {
+		doc.AddExtension(GatewayResponsesExtension, responses)
+	}
+}
+
+func (r GatewayResponse) render() model.AWSAPIGatewayGatewayResponse {
+	response := model.AWSAPIGatewayGatewayResponse{StatusCode: r.StatusCode, ResponseTemplates: r.Templates}
+	for name, value := range r.Headers {
+		if response.ResponseParameters == nil {
+			response.ResponseParameters = map[string]string{}
+		}
+		if !isMappingExpression(value) {
+			value = quote(value)
+		}
+		response.ResponseParameters[responseHeader(name)] = value
+	}
+	return response
+}
+
+// validateGatewayResponses checks the configured gateway responses
+func validateGatewayResponses(responses map[string]GatewayResponse) []string {
+	var types []string
+	for responseType := range responses {
+		types = append(types, responseType)
+	}
+	sort.Strings(types)
+
+	var problems []string
+	for _, responseType := range types {
+		if !contains(gatewayResponseTypes, strings.ToUpper(responseType)) {
+			problems = append(problems, fmt.Sprintf("unsupported gateway response type %q, expected one of %s", responseType, strings.Join(gatewayResponseTypes, ", ")))
+		}
+		if code := responses[responseType].StatusCode; code != "" && !statusCodeRegexp.MatchString(code) {
+			problems = append(problems, fmt.Sprintf("invalid status code %q of the %s gateway response", code, responseType))
+		}
+	}
+	return problems
+}
+
+func responseHeader(name string) string {
+	return fmt.Sprintf("gatewayresponse.header.%s", name)
+}
+
+// isMappingExpression tells whether the value is quoted or references the request, the context or the stage variables
+func isMappingExpression(value string) bool {
+	for _, prefix := range []string{"'", "method.request.", "context.", "stageVariables."} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func quote(value string) string {
+	return fmt.Sprintf("'%s'", value)
+}
+
+// gatewayCorsHeaders returns the cors headers of the gateway responses, with several allowed origins the origin of the
+// request is returned unless the credentials are allowed
+func gatewayCorsHeaders(options Options) map[string]string {
+	cors := options.Cors
+	headers := map[string]string{
+		allowOriginHeader:  quote(cors.allowOrigin()),
+		allowHeadersHeader: quote(strings.Join(cors.allowHeaders(options.CustomHeaders), ",")),
+	}
+	if cors.severalOrigins() && !cors.AllowCredentials {
+		headers[allowOriginHeader] = "method.request.header.Origin"
+	}
+	if cors.AllowCredentials {
+		headers[allowCredentialsHeader] = quote("true")
+	}
+	return headers
+}
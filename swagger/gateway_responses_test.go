@@ -0,0 +1,69 @@
+package swagger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/akhettar/apigw-pub/model"
+)
+
+func TestRenderSwagger_ShouldAddTheGatewayResponsesWithCors(t *testing.T) {
+
+	t.Logf("Given a custom UNAUTHORIZED gateway response and cors enabled")
+	{
+		options := OptionsFromEnv()
+		options.CorsEnabled = true
+		options.GatewayResponses = map[string]GatewayResponse{
+			"unauthorized": {
+				StatusCode: "401",
+				Headers:    map[string]string{"WWW-Authenticate": "Bearer", "X-Request-Id": "context.requestId"},
+				Templates:  map[string]string{"application/json": `{"message": $context.error.messageString}`},
+			},
+		}
+
+		t.Logf("\tWhen rendering the swagger, the gateway responses should be added")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(readAccountSwagger(t))
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered struct {
+				Responses map[string]model.AWSAPIGatewayGatewayResponse `json:"x-amazon-apigateway-gateway-responses"`
+			}
+			json.Unmarshal(data, &rendered)
+
+			unauthorized := rendered.Responses["UNAUTHORIZED"]
+			if unauthorized.StatusCode == "401" && unauthorized.ResponseParameters["gatewayresponse.header.WWW-Authenticate"] == "'Bearer'" &&
+				unauthorized.ResponseParameters["gatewayresponse.header.X-Request-Id"] == "context.requestId" && len(unauthorized.ResponseTemplates) == 1 {
+				t.Logf("\t\tThe configured response should be added with its quoted headers %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe configured response should be added with its quoted headers, got %+v %v", unauthorized, BallotX)
+			}
+
+			for _, responseType := range []string{"UNAUTHORIZED", Default4XX, Default5XX} {
+				if rendered.Responses[responseType].ResponseParameters["gatewayresponse.header.Access-Control-Allow-Origin"] == "'*'" {
+					t.Logf("\t\tThe %s response should have the cors headers %v", responseType, CheckMark)
+				} else {
+					t.Errorf("\t\tThe %s response should have the cors headers, got %+v %v", responseType, rendered.Responses, BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestOptionsValidate_ShouldReportUnknownGatewayResponses(t *testing.T) {
+
+	t.Logf("Given an unknown gateway response type")
+	{
+		options := Options{APIGatewayName: "api-gw-dev", AuthType: IAMAuth, GatewayResponses: map[string]GatewayResponse{"TEAPOT": {StatusCode: "418"}}}
+
+		t.Logf("\tWhen validating the options, the type should be reported")
+		{
+			if problems := options.Validate(); len(problems) == 1 {
+				t.Logf("\t\tThe unknown type should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe unknown type should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
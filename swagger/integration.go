@@ -0,0 +1,196 @@
+package swagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// IntegrationExtension the operation or path extension selecting the backend of the operations
+	IntegrationExtension = "x-integration"
+
+	HTTPIntegration      = "http"
+	HTTPProxyIntegration = "http_proxy"
+	LambdaIntegration    = "lambda"
+	AWSIntegration       = "aws"
+
+	AWSRegion    = "AWS_REGION"
+	AWSAccountID = "AWS_ACCOUNT_ID"
+
+	lambdaProxyType    = "aws_proxy"
+	internetConnection = "INTERNET"
+	lambdaArnPrefix    = "arn:aws:lambda:"
+)
+
+// Integration the backend of an operation, set with the `x-integration` extension of the operation or of its path, or
+// in the configuration
+type Integration struct {
+	// Type one of http (default), http_proxy, lambda or aws
+	Type string `json:"type" yaml:"type"`
+	// Function the lambda function name or arn, with an optional `:alias` suffix
+	Function string `json:"function,omitempty" yaml:"function"`
+	Alias    string `json:"alias,omitempty" yaml:"alias"`
+	// Service the aws service called by an aws integration, exp: sqs or dynamodb
+	Service string `json:"service,omitempty" yaml:"service"`
+	// Action or Path the aws service action or path, exp: SendMessage or 123456789012/orders-queue
+	Action            string            `json:"action,omitempty" yaml:"action"`
+	Path              string            `json:"path,omitempty" yaml:"path"`
+	HTTPMethod        string            `json:"httpMethod,omitempty" yaml:"httpMethod"`
+	Credentials       string            `json:"credentials,omitempty" yaml:"credentials"`
+	RequestTemplates  map[string]string `json:"requestTemplates,omitempty" yaml:"requestTemplates"`
+	RequestParameters map[string]string `json:"requestParameters,omitempty" yaml:"requestParameters"`
+}
+
+// Validate returns the problems of the integration
+func (i Integration) Validate(accountID string) []string {
+	var problems []string
+	switch i.Type {
+	case "", HTTPIntegration, HTTPProxyIntegration:
+	case LambdaIntegration:
+		if i.Function == "" {
+			problems = append(problems, "the function of a lambda integration is required")
+		} else if !strings.HasPrefix(i.Function, lambdaArnPrefix) && accountID == "" {
+			problems = append(problems, fmt.Sprintf("the aws account id (%s) is required to build the arn of the lambda function %s", AWSAccountID, i.Function))
+		}
+	case AWSIntegration:
+		if i.Service == "" {
+			problems = append(problems, "the service of an aws integration is required")
+		}
+		if (i.Action == "") == (i.Path == "") {
+			problems = append(problems, "either the action or the path of an aws integration is required")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported integration type %q, expected one of %s, %s, %s, %s",
+			i.Type, HTTPIntegration, HTTPProxyIntegration, LambdaIntegration, AWSIntegration))
+	}
+	return problems
+}
+
+// resolveIntegration returns the integration of the operation: its `x-integration` extension, then the one of its path,
+// then the configured one for the route or the path. The http integration is used by default.
+func resolveIntegration(path swg.PathItem, operation Operation, key string, options Options) (Integration, error) {
+	for _, extensions := range []swg.Extensions{operation.Extensions, path.Extensions} {
+		if value, ok := extensions[IntegrationExtension]; ok {
+			var integration Integration
+			if err := decodeExtension(value, &integration); err != nil {
+				return integration, fmt.Errorf("invalid %s extension on %s %s: %s", IntegrationExtension, operation.Method, key, err)
+			}
+			return integration, validateIntegration(integration, operation.Method, key, options)
+		}
+	}
+	for _, route := range []string{fmt.Sprintf("%s %s", operation.Method, key), key} {
+		if integration, ok := options.Integrations[route]; ok {
+			return integration, validateIntegration(integration, operation.Method, key, options)
+		}
+	}
+	return Integration{Type: HTTPIntegration}, nil
+}
+
+// decodeExtension decodes the value of an extension into the target, the unknown fields are rejected so that a typo
+// does not silently fall back to the default
+func decodeExtension(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+func validateIntegration(integration Integration, method, key string, options Options) error {
+	if problems := integration.Validate(options.AccountID); len(problems) > 0 {
+		return fmt.Errorf("invalid integration of %s %s: %s", method, key, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// apply sets the backend of the generated integration
+func (i Integration) apply(extension *model.AWSAPIGatewayIntegration, options Options) {
+	switch i.Type {
+	case HTTPProxyIntegration:
+		extension.IntegrationType = HTTPProxyIntegration
+		extension.PassthroughBehavior = "when_no_match"
+		extension.Responses = nil
+	case LambdaIntegration:
+		// lambda functions are always invoked with a POST, the request is passed as is to the function
+		extension.IntegrationType = lambdaProxyType
+		extension.URI = fmt.Sprintf("arn:aws:apigateway:%s:lambda:path/2015-03-31/functions/%s/invocations", options.region(), i.functionArn(options))
+		extension.HTTPMethod = http.MethodPost
+		extension.ConnectionType = internetConnection
+		extension.ConnectionID = ""
+		extension.PassthroughBehavior = "when_no_match"
+		extension.RequestParameters = nil
+		extension.Responses = nil
+		extension.Credentials = i.Credentials
+	case AWSIntegration:
+		action := "action/" + i.Action
+		if i.Path != "" {
+			action = "path/" + strings.TrimLeft(i.Path, "/")
+		}
+		extension.IntegrationType = AWSIntegration
+		extension.URI = fmt.Sprintf("arn:aws:apigateway:%s:%s:%s", options.region(), i.Service, action)
+		extension.HTTPMethod = http.MethodPost
+		if i.HTTPMethod != "" {
+			extension.HTTPMethod = strings.ToUpper(i.HTTPMethod)
+		}
+		extension.ConnectionType = internetConnection
+		extension.ConnectionID = ""
+		extension.Credentials = i.Credentials
+		extension.RequestParameters = i.RequestParameters
+		extension.RequestTemplates = i.RequestTemplates
+		if len(i.RequestTemplates) > 0 {
+			extension.PassthroughBehavior = "never"
+		}
+		return
+	}
+
+	if i.HTTPMethod != "" {
+		extension.HTTPMethod = strings.ToUpper(i.HTTPMethod)
+	}
+	for name, value := range i.RequestParameters {
+		if extension.RequestParameters == nil {
+			extension.RequestParameters = map[string]string{}
+		}
+		extension.RequestParameters[name] = value
+	}
+	if len(i.RequestTemplates) > 0 {
+		extension.RequestTemplates = i.RequestTemplates
+	}
+}
+
+// functionArn returns the arn of the lambda function, built from its name and alias when not given as an arn
+func (i Integration) functionArn(options Options) string {
+	arn := i.Function
+	if !strings.HasPrefix(arn, lambdaArnPrefix) {
+		arn = fmt.Sprintf("%s%s:%s:function:%s", lambdaArnPrefix, options.region(), options.AccountID, i.Function)
+	}
+	if i.Alias != "" {
+		arn = fmt.Sprintf("%s:%s", arn, i.Alias)
+	}
+	return arn
+}
+
+// validateIntegrations checks the integrations of the configuration
+func validateIntegrations(integrations map[string]Integration, accountID string) []string {
+	var routes []string
+	for route := range integrations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var problems []string
+	for _, route := range routes {
+		for _, problem := range integrations[route].Validate(accountID) {
+			problems = append(problems, fmt.Sprintf("integration of %s: %s", route, problem))
+		}
+	}
+	return problems
+}
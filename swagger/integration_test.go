@@ -0,0 +1,128 @@
+package swagger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldIntegrateLambdaAndAWSServices(t *testing.T) {
+
+	t.Logf("Given operations are integrated with lambda functions, aws services and http proxies")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		item.Get.AddExtension(IntegrationExtension, map[string]interface{}{"type": "lambda", "function": "account-reader", "alias": "live"})
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+
+		item = doc.Paths.Paths["/admin/accounts"]
+		item.AddExtension(IntegrationExtension, map[string]interface{}{
+			"type":             "aws",
+			"service":          "sqs",
+			"path":             "123456789012/accounts",
+			"credentials":      "arn:aws:iam::123456789012:role/apigw-sqs",
+			"requestTemplates": map[string]string{"application/json": "Action=SendMessage&MessageBody=$input.body"},
+		})
+		doc.Paths.Paths["/admin/accounts"] = item
+
+		options := OptionsFromEnv()
+		options.Region = "eu-west-2"
+		options.AccountID = "123456789012"
+		options.Integrations = map[string]Integration{"PUT /organisations/{orgId}": {Type: HTTPProxyIntegration}}
+
+		t.Logf("\tWhen rendering the swagger, each operation should be integrated with its backend")
+		{
+			data, err := NewSwaggerClientWithOptions("account-service", options).RenderSwagger(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			lambda := integrationOf(rendered.Paths.Paths["/accounts/{accountId}"].Get)
+			expectedUri := "arn:aws:apigateway:eu-west-2:lambda:path/2015-03-31/functions/arn:aws:lambda:eu-west-2:123456789012:function:account-reader:live/invocations"
+			if lambda.IntegrationType == "aws_proxy" && lambda.URI == expectedUri && lambda.HTTPMethod == "POST" {
+				t.Logf("\t\tThe lambda function should be invoked with an aws_proxy integration %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe lambda function should be invoked with an aws_proxy integration, got %+v %v", lambda, BallotX)
+			}
+
+			sqs := integrationOf(rendered.Paths.Paths["/admin/accounts"].Post)
+			if sqs.IntegrationType == "aws" && sqs.URI == "arn:aws:apigateway:eu-west-2:sqs:path/123456789012/accounts" &&
+				sqs.Credentials != "" && sqs.PassthroughBehavior == "never" && len(sqs.RequestTemplates) == 1 {
+				t.Logf("\t\tThe path integration should send the message to sqs %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe path integration should send the message to sqs, got %+v %v", sqs, BallotX)
+			}
+
+			proxy := integrationOf(rendered.Paths.Paths["/organisations/{orgId}"].Put)
+			other := integrationOf(rendered.Paths.Paths["/organisations/{orgId}"].Get)
+			if proxy.IntegrationType == "http_proxy" && other.IntegrationType == "http" {
+				t.Logf("\t\tThe configured route should use the http_proxy integration %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe configured route should use the http_proxy integration, got %s and %s %v", proxy.IntegrationType, other.IntegrationType, BallotX)
+			}
+
+			if !strings.Contains(string(data), IntegrationExtension+"\"") {
+				t.Logf("\t\tThe %s extensions should be removed %v", IntegrationExtension, CheckMark)
+			} else {
+				t.Errorf("\t\tThe %s extensions should be removed %v", IntegrationExtension, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given a lambda integration without an account id")
+	{
+		doc := readAccountSwagger(t)
+		doc.Paths.Paths["/accounts/{accountId}"].Get.AddExtension(IntegrationExtension, map[string]interface{}{"type": "lambda", "function": "account-reader"})
+
+		t.Logf("\tWhen rendering the swagger, it should fail")
+		{
+			_, err := NewSwaggerClient("account-service").RenderSwagger(doc)
+			if err != nil && strings.Contains(err.Error(), "GET /accounts/{accountId}") && strings.Contains(err.Error(), AWSAccountID) {
+				t.Logf("\t\tThe missing account id should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe missing account id should be reported, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldDeclareAResponseOfTheProxyIntegrationsWithoutResponses(t *testing.T) {
+
+	for _, integration := range []map[string]interface{}{
+		{"type": "http_proxy"},
+		{"type": "lambda", "function": "arn:aws:lambda:eu-west-1:123456789012:function:status"},
+	} {
+		t.Logf("Given a %s integration of an operation declaring no responses", integration["type"])
+		{
+			doc := readAccountSwagger(t)
+			op := swg.NewOperation("status")
+			op.AddExtension(IntegrationExtension, integration)
+			doc.Paths.Paths["/status"] = swg.PathItem{PathItemProps: swg.PathItemProps{Get: op}}
+
+			t.Logf("\tWhen rendering the swagger with cors enabled, a response with the cors headers should be declared")
+			{
+				options := OptionsFromEnv()
+				options.CorsEnabled = true
+				data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+				if err != nil {
+					t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+				}
+				var rendered swg.Swagger
+				json.Unmarshal(data, &rendered)
+
+				get := rendered.Paths.Paths["/status"].Get
+				if get.Responses != nil {
+					if _, ok := get.Responses.StatusCodeResponses[200].Headers["Access-Control-Allow-Origin"]; ok {
+						t.Logf("\t\tThe 200 response should be declared with the cors headers %v", CheckMark)
+						continue
+					}
+				}
+				t.Errorf("\t\tThe 200 response should be declared with the cors headers, got %v %v", get.Responses, BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package swagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	swg "github.com/go-openapi/spec"
+	log "github.com/sirupsen/logrus"
+)
+
+// the sections of the swagger document which can be referenced, they are namespaced when colliding between services
+var referencedSections = []string{"definitions", "parameters", "responses"}
+
+var nonAlphaNumRegexp = regexp.MustCompile("[^A-Za-z0-9]+")
+
+// Service one of the services fronted by the REST API, its paths are published under the base path prefix and
+// integrated with the endpoint url of its own render options
+type Service struct {
+	Name       string
+	SwaggerURL string
+	BasePath   string
+	Options    Options
+}
+
+// renderedService the swagger document of a service with the AWS extensions added
+type renderedService struct {
+	Service
+	doc    swg.Swagger
+	report RenderReport
+}
+
+// RenderServices - Function
+// Fetches and renders the swagger document of every service and merges them into a single document published as the
+// given api gateway. Colliding definitions are namespaced with the service name and duplicated paths are reported.
+func RenderServices(apiGatewayName string, services []Service) ([]byte, RenderReport, error) {
+	var rendered []renderedService
+	for _, service := range services {
+		log.WithFields(log.Fields{"service": service.Name, "basePath": service.BasePath}).Info("Rendering service swagger")
+		client := NewSwaggerClientWithOptions(service.SwaggerURL, service.Options)
+		doc, err := client.FetchSwagger()
+		if err != nil {
+			return nil, RenderReport{}, fmt.Errorf("service %s: %s", service.Name, err)
+		}
+		renderedDoc, report, err := client.render(doc)
+		if err != nil {
+			return nil, RenderReport{}, fmt.Errorf("service %s: %s", service.Name, err)
+		}
+		rendered = append(rendered, renderedService{Service: service, doc: renderedDoc, report: report})
+	}
+
+	merged, report, err := mergeServices(apiGatewayName, rendered)
+	if err != nil {
+		return nil, report, err
+	}
+	json, err := merged.MarshalJSON()
+	return json, report, err
+}
+
+// mergeServices merges the rendered documents into one, all the conflicts are reported at once
+func mergeServices(apiGatewayName string, services []renderedService) (swg.Swagger, RenderReport, error) {
+	var report RenderReport
+	merged := swg.Swagger{SwaggerProps: swg.SwaggerProps{
+		Swagger:             "2.0",
+		Info:                &swg.Info{InfoProps: swg.InfoProps{Title: apiGatewayName}},
+		Paths:               &swg.Paths{Paths: map[string]swg.PathItem{}},
+		Definitions:         swg.Definitions{},
+		Parameters:          map[string]swg.Parameter{},
+		Responses:           map[string]swg.Response{},
+		SecurityDefinitions: swg.SecurityDefinitions{},
+	}}
+
+	var problems []string
+	for i, service := range namespaceCollisions(services) {
+		for key, path := range service.doc.Paths.Paths {
+			fullPath := joinPath(service.BasePath, key)
+			if _, ok := merged.Paths.Paths[fullPath]; ok {
+				problems = append(problems, fmt.Sprintf("path %s is published by both %s and %s", fullPath, pathOwner(services[:i], fullPath), service.Name))
+				continue
+			}
+			merged.Paths.Paths[fullPath] = path
+		}
+
+		for name, schema := range service.doc.Definitions {
+			merged.Definitions[name] = schema
+		}
+		for name, param := range service.doc.Parameters {
+			merged.Parameters[name] = param
+		}
+		for name, response := range service.doc.Responses {
+			merged.Responses[name] = response
+		}
+		for name, scheme := range service.doc.SecurityDefinitions {
+			if existing, ok := merged.SecurityDefinitions[name]; ok && !sameJSON(existing, scheme) {
+				problems = append(problems, fmt.Sprintf("authorizer %s of %s is defined differently by another service", name, service.Name))
+				continue
+			}
+			merged.SecurityDefinitions[name] = scheme
+		}
+		// the validators are the same for every service
+		if validators, ok := service.doc.Extensions[RequestValidatorsExtension]; ok {
+			merged.AddExtension(RequestValidatorsExtension, validators)
+		}
+		if responses, ok := service.doc.Extensions[GatewayResponsesExtension]; ok {
+			if existing, ok := merged.Extensions[GatewayResponsesExtension]; ok && !sameJSON(existing, responses) {
+				problems = append(problems, fmt.Sprintf("the gateway responses of %s are defined differently by another service", service.Name))
+			} else {
+				merged.AddExtension(GatewayResponsesExtension, responses)
+			}
+		}
+		if policy, ok := service.doc.Extensions[PolicyExtension]; ok {
+			if existing, ok := merged.Extensions[PolicyExtension]; ok && !sameJSON(existing, policy) {
+				problems = append(problems, fmt.Sprintf("the resource policy of %s is defined differently by another service", service.Name))
+			} else {
+				merged.AddExtension(PolicyExtension, policy)
+			}
+		}
+		merged.Tags = append(merged.Tags, service.doc.Tags...)
+		report.merge(service.report, service.BasePath)
+	}
+
+	report.sort()
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return merged, report, fmt.Errorf("failed to merge the services: %s", strings.Join(problems, "; "))
+	}
+	return merged, report, nil
+}
+
+// namespaceCollisions renames the definitions, parameters and responses defined differently by several services, they
+// are prefixed with the service name in every service defining them
+func namespaceCollisions(services []renderedService) []renderedService {
+	for _, section := range referencedSections {
+		// the json of every entry per name, for each service
+		defined := map[string]map[string]string{}
+		for i, service := range services {
+			for name, entry := range sectionEntries(service.doc, section) {
+				if defined[name] == nil {
+					defined[name] = map[string]string{}
+				}
+				defined[name][services[i].Name] = entry
+			}
+		}
+
+		for name, entries := range defined {
+			if !collides(entries) {
+				continue
+			}
+			for i := range services {
+				if _, ok := entries[services[i].Name]; ok {
+					renamed := namespace(services[i].Name, name)
+					log.WithFields(log.Fields{"service": services[i].Name, "from": name, "to": renamed}).Info("Namespacing colliding " + section)
+					services[i].doc = renameReference(services[i].doc, section, name, renamed)
+				}
+			}
+		}
+	}
+	return services
+}
+
+func sectionEntries(doc swg.Swagger, section string) map[string]string {
+	entries := map[string]string{}
+	add := func(name string, value interface{}) {
+		data, _ := json.Marshal(value)
+		entries[name] = string(data)
+	}
+	switch section {
+	case "definitions":
+		for name, schema := range doc.Definitions {
+			add(name, schema)
+		}
+	case "parameters":
+		for name, param := range doc.Parameters {
+			add(name, param)
+		}
+	case "responses":
+		for name, response := range doc.Responses {
+			add(name, response)
+		}
+	}
+	return entries
+}
+
+// collides tells whether the services define an entry differently, identical entries are shared
+func collides(entries map[string]string) bool {
+	first := ""
+	for _, entry := range entries {
+		if first == "" {
+			first = entry
+		} else if entry != first {
+			return true
+		}
+	}
+	return false
+}
+
+// renameReference renames an entry of the given section along with all its references
+func renameReference(doc swg.Swagger, section, name, renamed string) swg.Swagger {
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		return doc
+	}
+	from := fmt.Sprintf("%q", fmt.Sprintf("#/%s/%s", section, name))
+	to := fmt.Sprintf("%q", fmt.Sprintf("#/%s/%s", section, renamed))
+	data = bytes.ReplaceAll(data, []byte(from), []byte(to))
+
+	var renamedDoc swg.Swagger
+	if err := renamedDoc.UnmarshalJSON(data); err != nil {
+		return doc
+	}
+	switch section {
+	case "definitions":
+		renamedDoc.Definitions[renamed] = renamedDoc.Definitions[name]
+		delete(renamedDoc.Definitions, name)
+	case "parameters":
+		renamedDoc.Parameters[renamed] = renamedDoc.Parameters[name]
+		delete(renamedDoc.Parameters, name)
+	case "responses":
+		renamedDoc.Responses[renamed] = renamedDoc.Responses[name]
+		delete(renamedDoc.Responses, name)
+	}
+	return renamedDoc
+}
+
+// namespace prefixes the name with the service name, API Gateway model names must be alphanumeric
+func namespace(service, name string) string {
+	var builder strings.Builder
+	for _, part := range nonAlphaNumRegexp.Split(service, -1) {
+		if part != "" {
+			builder.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	builder.WriteString(name)
+	return builder.String()
+}
+
+func pathOwner(services []renderedService, fullPath string) string {
+	for _, service := range services {
+		for key := range service.doc.Paths.Paths {
+			if joinPath(service.BasePath, key) == fullPath {
+				return service.Name
+			}
+		}
+	}
+	return ""
+}
+
+// joinPath prefixes the path with the base path
+func joinPath(basePath, path string) string {
+	basePath = strings.TrimRight(basePath, "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	return basePath + path
+}
+
+func sameJSON(a, b interface{}) bool {
+	dataA, _ := json.Marshal(a)
+	dataB, _ := json.Marshal(b)
+	return bytes.Equal(dataA, dataB)
+}
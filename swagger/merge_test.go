@@ -0,0 +1,105 @@
+package swagger
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+func mergeOptions(endpointUrl string) Options {
+	return Options{
+		APIGatewayName: "api-gw-dev",
+		EndpointURL:    endpointUrl,
+		AuthType:       CustomAuth,
+		AuthName:       ExpectedAuthorizerName,
+		AuthURL:        ExpectedAuthorizerArn,
+	}
+}
+
+func TestRenderServices_ShouldMergeTheServicesIntoOneDocument(t *testing.T) {
+
+	t.Logf("Given the gateway fronts the account, receipt and order services")
+	{
+		services := []Service{
+			{Name: "account", SwaggerURL: "../data/swagger.json", BasePath: "/account", Options: mergeOptions("account.internal")},
+			{Name: "receipts", SwaggerURL: "../data/swagger_header.json", BasePath: "/receipts", Options: mergeOptions("receipts.internal")},
+			{Name: "orders", SwaggerURL: "../data/openapi3.json", BasePath: "/orders", Options: mergeOptions("orders.internal")},
+		}
+
+		t.Logf("\tWhen rendering the services, their paths should be merged under their base paths")
+		{
+			data, report, err := RenderServices("api-gw-dev", services)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the services %v %v", err, BallotX)
+			}
+			var doc swg.Swagger
+			json.Unmarshal(data, &doc)
+
+			get := doc.Paths.Paths["/account/accounts/{accountId}"].Get
+			post := doc.Paths.Paths["/orders/orders"].Post
+			if get != nil && post != nil && doc.Paths.Paths["/receipts/receiptbank/callback"].Post != nil {
+				t.Logf("\t\tThe paths should be prefixed with the service base path %v", CheckMark)
+			} else {
+				t.Fatalf("\t\tThe paths should be prefixed with the service base path %v", BallotX)
+			}
+
+			var integration model.AWSAPIGatewayIntegration
+			raw, _ := json.Marshal(get.Extensions["x-amazon-apigateway-integration"])
+			json.Unmarshal(raw, &integration)
+			if integration.URI == "http://account.internal/accounts/{accountId}" {
+				t.Logf("\t\tEach service should be integrated with its own endpoint %v", CheckMark)
+			} else {
+				t.Errorf("\t\tEach service should be integrated with its own endpoint, got %s %v", integration.URI, BallotX)
+			}
+
+			_, shared := doc.Definitions["HttpExceptionResponse"]
+			_, account := doc.Definitions["AccountHttpExceptionResponse"]
+			_, orders := doc.Definitions["OrdersHttpExceptionResponse"]
+			if !shared && account && orders {
+				t.Logf("\t\tColliding definitions should be namespaced with the service name %v", CheckMark)
+			} else {
+				t.Errorf("\t\tColliding definitions should be namespaced with the service name %v", BallotX)
+			}
+
+			var missing []string
+			for _, ref := range regexp.MustCompile(`#/definitions/(\w+)`).FindAllStringSubmatch(string(data), -1) {
+				if _, ok := doc.Definitions[ref[1]]; !ok {
+					missing = append(missing, ref[1])
+				}
+			}
+			if len(missing) == 0 {
+				t.Logf("\t\tAll the references should resolve to a merged definition %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAll the references should resolve to a merged definition, missing %v %v", missing, BallotX)
+			}
+
+			if doc.Info.Title == "api-gw-dev" && strings.Contains(report.String(), "/orders/orders -> [http] https://orders.internal/orders") {
+				t.Logf("\t\tThe report should list the merged integrations %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe report should list the merged integrations, got %s %v", report, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given two services publish the same paths")
+	{
+		services := []Service{
+			{Name: "account", SwaggerURL: "../data/swagger.json", BasePath: "/", Options: mergeOptions("account.internal")},
+			{Name: "account-v2", SwaggerURL: "../data/swagger.json", Options: mergeOptions("account-v2.internal")},
+		}
+
+		t.Logf("\tWhen rendering the services, the duplicate paths should be reported")
+		{
+			_, _, err := RenderServices("api-gw-dev", services)
+			if err != nil && strings.Contains(err.Error(), "path /organisations/{orgId} is published by both account and account-v2") {
+				t.Logf("\t\tAn error should list the duplicate paths %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAn error should list the duplicate paths, got %v %v", err, BallotX)
+			}
+		}
+	}
+}
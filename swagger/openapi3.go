@@ -0,0 +1,511 @@
+package swagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	swg "github.com/go-openapi/spec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	OpenAPI3Version = "3."
+	formUrlEncoded  = "application/x-www-form-urlencoded"
+	multipartForm   = "multipart/form-data"
+)
+
+// component references rewritten to their swagger 2.0 location
+// componentRefs the swagger 2.0 location of the components, keyed by kind
+var componentRefs = map[string]string{
+	"schemas":    "#/definitions/",
+	"parameters": "#/parameters/",
+	"responses":  "#/responses/",
+}
+
+// componentRefRegexp matches the `$ref` values pointing at the components, the other strings are left as is
+var componentRefRegexp = regexp.MustCompile(`("\$ref"\s*:\s*")#/components/(schemas|parameters|responses)/`)
+
+var serverVariableRegexp = regexp.MustCompile(`{([^}]+)}`)
+
+// The subset of the OpenAPI 3.x document required to produce a swagger 2.0 document that can be rendered
+type openAPI3 struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       *swg.Info                  `json:"info"`
+	Servers    []server3                  `json:"servers"`
+	Paths      map[string]json.RawMessage `json:"paths"`
+	Components components3                `json:"components"`
+	Security   []map[string][]string      `json:"security"`
+	Tags       []swg.Tag                  `json:"tags"`
+}
+
+type server3 struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+type components3 struct {
+	Schemas         map[string]swg.Schema      `json:"schemas"`
+	Parameters      map[string]parameter3      `json:"parameters"`
+	Responses       map[string]response3       `json:"responses"`
+	RequestBodies   map[string]requestBody3    `json:"requestBodies"`
+	SecuritySchemes map[string]json.RawMessage `json:"securitySchemes"`
+}
+
+type operation3 struct {
+	Tags        []string              `json:"tags"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description"`
+	OperationID string                `json:"operationId"`
+	Deprecated  bool                  `json:"deprecated"`
+	Parameters  []parameter3          `json:"parameters"`
+	RequestBody *requestBody3         `json:"requestBody"`
+	Responses   map[string]response3  `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type parameter3 struct {
+	Ref         string      `json:"$ref"`
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description"`
+	Required    bool        `json:"required"`
+	Schema      *swg.Schema `json:"schema"`
+}
+
+type requestBody3 struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Content     map[string]mediaType3 `json:"content"`
+}
+
+type response3 struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Headers     map[string]header3    `json:"headers"`
+	Content     map[string]mediaType3 `json:"content"`
+}
+
+type header3 struct {
+	Description string      `json:"description"`
+	Schema      *swg.Schema `json:"schema"`
+}
+
+type mediaType3 struct {
+	Schema *swg.Schema `json:"schema"`
+}
+
+type securityScheme3 struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Scheme      string `json:"scheme"`
+	Flows       map[string]struct {
+		AuthorizationURL string            `json:"authorizationUrl"`
+		TokenURL         string            `json:"tokenUrl"`
+		Scopes           map[string]string `json:"scopes"`
+	} `json:"flows"`
+}
+
+// parseSwagger decodes the given document, converting it to swagger 2.0 when it is an OpenAPI 3.x document
+func parseSwagger(data []byte) (swg.Swagger, error) {
+	var version struct {
+		OpenAPI string `json:"openapi"`
+	}
+	var doc swg.Swagger
+	if err := json.Unmarshal(data, &version); err != nil {
+		return doc, err
+	}
+
+	if strings.HasPrefix(version.OpenAPI, OpenAPI3Version) {
+		log.WithFields(log.Fields{"version": version.OpenAPI}).Info("Converting OpenAPI 3 document to swagger 2.0")
+		return convertOpenAPI3(data)
+	}
+	err := json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// convertOpenAPI3 converts an OpenAPI 3.x document into its swagger 2.0 equivalent so it can go through the same rendering
+// as any other swagger document. Vendor extensions such as `x-publish` and `x-auth-disabled` are carried over as is.
+func convertOpenAPI3(data []byte) (swg.Swagger, error) {
+	data = componentRefRegexp.ReplaceAllFunc(data, func(ref []byte) []byte {
+		match := componentRefRegexp.FindSubmatch(ref)
+		return append(append([]byte{}, match[1]...), componentRefs[string(match[2])]...)
+	})
+
+	var doc3 openAPI3
+	var swagger swg.Swagger
+	if err := json.Unmarshal(data, &doc3); err != nil {
+		return swagger, err
+	}
+
+	swagger.Swagger = "2.0"
+	swagger.Info = doc3.Info
+	swagger.Tags = doc3.Tags
+	swagger.Security = doc3.Security
+	swagger.Definitions = doc3.Components.Schemas
+	swagger.Extensions = vendorExtensions(data)
+
+	if err := applyServer(&swagger, doc3.Servers); err != nil {
+		return swagger, err
+	}
+
+	securityDefinitions, err := convertSecuritySchemes(doc3.Components.SecuritySchemes)
+	if err != nil {
+		return swagger, err
+	}
+	swagger.SecurityDefinitions = securityDefinitions
+
+	swagger.Paths = &swg.Paths{Paths: map[string]swg.PathItem{}}
+	for key, raw := range doc3.Paths {
+		pathItem, err := convertPathItem(raw, doc3.Components)
+		if err != nil {
+			return swagger, fmt.Errorf("failed to convert path %s: %v", key, err)
+		}
+		swagger.Paths.Paths[key] = pathItem
+	}
+	return swagger, nil
+}
+
+// The first server is used to set the host, basePath and schemes of the swagger 2.0 document
+func applyServer(swagger *swg.Swagger, servers []server3) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	server := servers[0]
+	rawUrl := serverVariableRegexp.ReplaceAllStringFunc(server.URL, func(variable string) string {
+		return server.Variables[strings.Trim(variable, "{}")].Default
+	})
+
+	serverUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return fmt.Errorf("invalid server url %s: %v", server.URL, err)
+	}
+	if serverUrl.Scheme != "" {
+		swagger.Schemes = []string{serverUrl.Scheme}
+	}
+	swagger.Host = serverUrl.Host
+	swagger.BasePath = serverUrl.Path
+	return nil
+}
+
+func convertSecuritySchemes(schemes map[string]json.RawMessage) (map[string]*swg.SecurityScheme, error) {
+	if len(schemes) == 0 {
+		return nil, nil
+	}
+	definitions := map[string]*swg.SecurityScheme{}
+	for name, raw := range schemes {
+		var scheme3 securityScheme3
+		if err := json.Unmarshal(raw, &scheme3); err != nil {
+			return nil, err
+		}
+
+		scheme := &swg.SecurityScheme{}
+		scheme.Description = scheme3.Description
+		switch scheme3.Type {
+		case "apiKey":
+			scheme.Type = "apiKey"
+			scheme.Name = scheme3.Name
+			scheme.In = scheme3.In
+		case "http":
+			if strings.ToLower(scheme3.Scheme) == "basic" {
+				scheme.Type = "basic"
+			} else {
+				// bearer tokens are sent in the Authorization header, which is how API Gateway authorizers read them
+				scheme.Type = "apiKey"
+				scheme.Name = "Authorization"
+				scheme.In = "header"
+			}
+		case "oauth2":
+			scheme.Type = "oauth2"
+			// swagger 2.0 only allows a single flow per scheme
+			for _, flowName := range []string{"authorizationCode", "implicit", "clientCredentials", "password"} {
+				flow, ok := scheme3.Flows[flowName]
+				if !ok {
+					continue
+				}
+				scheme.Flow = oauth2Flows[flowName]
+				scheme.AuthorizationURL = flow.AuthorizationURL
+				scheme.TokenURL = flow.TokenURL
+				scheme.Scopes = flow.Scopes
+				break
+			}
+		default:
+			log.WithFields(log.Fields{"scheme": name, "type": scheme3.Type}).Warn("Unsupported security scheme type, skipping")
+			continue
+		}
+		scheme.Extensions = vendorExtensions(raw)
+		definitions[name] = scheme
+	}
+	return definitions, nil
+}
+
+var oauth2Flows = map[string]string{
+	"implicit":          "implicit",
+	"password":          "password",
+	"clientCredentials": "application",
+	"authorizationCode": "accessCode",
+}
+
+func convertPathItem(raw json.RawMessage, components components3) (swg.PathItem, error) {
+	var item map[string]json.RawMessage
+	var pathItem swg.PathItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return pathItem, err
+	}
+	pathItem.Extensions = vendorExtensions(raw)
+
+	var shared []parameter3
+	if params, ok := item["parameters"]; ok {
+		if err := json.Unmarshal(params, &shared); err != nil {
+			return pathItem, err
+		}
+		for _, param := range shared {
+			if converted, ok := convertParameter(param, components); ok {
+				pathItem.Parameters = append(pathItem.Parameters, converted)
+			}
+		}
+	}
+
+	operations := map[string]**swg.Operation{
+		http.MethodGet:     &pathItem.Get,
+		http.MethodPut:     &pathItem.Put,
+		http.MethodPost:    &pathItem.Post,
+		http.MethodDelete:  &pathItem.Delete,
+		http.MethodOptions: &pathItem.Options,
+		http.MethodHead:    &pathItem.Head,
+		http.MethodPatch:   &pathItem.Patch,
+	}
+	for method, target := range operations {
+		rawOp, ok := item[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+		op, err := convertOperation(rawOp, components)
+		if err != nil {
+			return pathItem, fmt.Errorf("%s: %v", method, err)
+		}
+		*target = op
+	}
+	return pathItem, nil
+}
+
+func convertOperation(raw json.RawMessage, components components3) (*swg.Operation, error) {
+	var op3 operation3
+	if err := json.Unmarshal(raw, &op3); err != nil {
+		return nil, err
+	}
+
+	op := swg.NewOperation(op3.OperationID)
+	op.Tags = op3.Tags
+	op.Summary = op3.Summary
+	op.Description = op3.Description
+	op.Deprecated = op3.Deprecated
+	op.Security = op3.Security
+	op.Extensions = vendorExtensions(raw)
+
+	for _, param := range op3.Parameters {
+		if converted, ok := convertParameter(param, components); ok {
+			op.Parameters = append(op.Parameters, converted)
+		}
+	}
+
+	if op3.RequestBody != nil {
+		body := resolveRequestBody(*op3.RequestBody, components)
+		op.Consumes = mediaTypes(body.Content)
+		op.Parameters = append(op.Parameters, convertRequestBody(body)...)
+	}
+
+	op.Responses = &swg.Responses{ResponsesProps: swg.ResponsesProps{StatusCodeResponses: map[int]swg.Response{}}}
+	produces := map[string]bool{}
+	for code, resp3 := range op3.Responses {
+		resp3 = resolveResponse(resp3, components)
+		response := convertResponse(resp3)
+		for mediaType := range resp3.Content {
+			produces[mediaType] = true
+		}
+
+		if code == "default" {
+			op.Responses.Default = &response
+			continue
+		}
+		statusCode, err := strconv.Atoi(code)
+		if err != nil {
+			log.WithFields(log.Fields{"code": code}).Warn("Status code ranges are not supported in swagger 2.0, skipping response")
+			continue
+		}
+		op.Responses.StatusCodeResponses[statusCode] = response
+	}
+	for mediaType := range produces {
+		op.Produces = append(op.Produces, mediaType)
+	}
+	sort.Strings(op.Produces)
+	return op, nil
+}
+
+func convertParameter(param3 parameter3, components components3) (swg.Parameter, bool) {
+	if param3.Ref != "" {
+		// parameter references have been rewritten to the swagger 2.0 location
+		name := strings.TrimPrefix(param3.Ref, "#/parameters/")
+		resolved, ok := components.Parameters[name]
+		if !ok {
+			log.WithFields(log.Fields{"ref": param3.Ref}).Warn("Unresolved parameter reference, skipping")
+			return swg.Parameter{}, false
+		}
+		param3 = resolved
+	}
+
+	if param3.In == "cookie" {
+		log.WithFields(log.Fields{"name": param3.Name}).Warn("Cookie parameters are not supported in swagger 2.0, skipping")
+		return swg.Parameter{}, false
+	}
+
+	param := swg.Parameter{ParamProps: swg.ParamProps{
+		Name:        param3.Name,
+		In:          param3.In,
+		Description: param3.Description,
+		Required:    param3.Required,
+	}}
+	param.SimpleSchema = simpleSchema(param3.Schema)
+	return param, true
+}
+
+func resolveRequestBody(body requestBody3, components components3) requestBody3 {
+	if body.Ref == "" {
+		return body
+	}
+	resolved, ok := components.RequestBodies[strings.TrimPrefix(body.Ref, "#/components/requestBodies/")]
+	if !ok {
+		log.WithFields(log.Fields{"ref": body.Ref}).Warn("Unresolved request body reference")
+		return body
+	}
+	return resolved
+}
+
+// A request body becomes either a single body parameter or, for form content, one formData parameter per property
+func convertRequestBody(body requestBody3) []swg.Parameter {
+	for _, form := range []string{multipartForm, formUrlEncoded} {
+		content, ok := body.Content[form]
+		if !ok || content.Schema == nil {
+			continue
+		}
+		var params []swg.Parameter
+		required := map[string]bool{}
+		for _, name := range content.Schema.Required {
+			required[name] = true
+		}
+		for name, property := range content.Schema.Properties {
+			property := property
+			param := swg.Parameter{ParamProps: swg.ParamProps{
+				Name:        name,
+				In:          "formData",
+				Description: property.Description,
+				Required:    required[name],
+			}}
+			param.SimpleSchema = simpleSchema(&property)
+			if property.Format == "binary" {
+				param.Type = "file"
+				param.Format = ""
+			}
+			params = append(params, param)
+		}
+		sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+		return params
+	}
+
+	schema := preferredSchema(body.Content)
+	if schema == nil {
+		return nil
+	}
+	return []swg.Parameter{{ParamProps: swg.ParamProps{
+		Name:        "body",
+		In:          "body",
+		Description: body.Description,
+		Required:    body.Required,
+		Schema:      schema,
+	}}}
+}
+
+func resolveResponse(resp response3, components components3) response3 {
+	if resp.Ref == "" {
+		return resp
+	}
+	resolved, ok := components.Responses[strings.TrimPrefix(resp.Ref, "#/responses/")]
+	if !ok {
+		log.WithFields(log.Fields{"ref": resp.Ref}).Warn("Unresolved response reference")
+		return resp
+	}
+	return resolved
+}
+
+func convertResponse(resp3 response3) swg.Response {
+	response := swg.Response{ResponseProps: swg.ResponseProps{
+		Description: resp3.Description,
+		Schema:      preferredSchema(resp3.Content),
+	}}
+	for name, header3 := range resp3.Headers {
+		if response.Headers == nil {
+			response.Headers = map[string]swg.Header{}
+		}
+		header := swg.Header{SimpleSchema: simpleSchema(header3.Schema)}
+		header.Description = header3.Description
+		response.Headers[name] = header
+	}
+	return response
+}
+
+// The json media type schema is preferred, otherwise the first one in alphabetical order
+func preferredSchema(content map[string]mediaType3) *swg.Schema {
+	if media, ok := content[DEFAULT_JSON_MIME_TYPE[0]]; ok {
+		return media.Schema
+	}
+	types := mediaTypes(content)
+	if len(types) == 0 {
+		return nil
+	}
+	return content[types[0]].Schema
+}
+
+func mediaTypes(content map[string]mediaType3) []string {
+	var types []string
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func simpleSchema(schema *swg.Schema) swg.SimpleSchema {
+	if schema == nil || len(schema.Type) == 0 {
+		return swg.SimpleSchema{Type: "string"}
+	}
+	simple := swg.SimpleSchema{Type: schema.Type[0], Format: schema.Format, Default: schema.Default}
+	if simple.Type == "array" && schema.Items != nil && schema.Items.Schema != nil {
+		items := swg.NewItems()
+		items.SimpleSchema = simpleSchema(schema.Items.Schema)
+		simple.Items = items
+	}
+	return simple
+}
+
+// Collects the `x-` prefixed vendor extensions of the given json object
+func vendorExtensions(raw []byte) swg.Extensions {
+	var extensible swg.VendorExtensible
+	if err := json.Unmarshal(raw, &extensible); err != nil || len(extensible.Extensions) == 0 {
+		return nil
+	}
+	extensions := swg.Extensions{}
+	for key, value := range extensible.Extensions {
+		extensions.Add(key, value)
+	}
+	return extensions
+}
@@ -0,0 +1,159 @@
+package swagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestParseSwagger_ShouldConvertOpenAPI3Document(t *testing.T) {
+
+	t.Logf("Given we read an OpenAPI 3 document")
+	{
+		t.Logf("\tWhen parsing the document, it should be converted to swagger 2.0")
+		{
+			data, _ := ioutil.ReadFile("../data/openapi3.json")
+			doc, err := parseSwagger(data)
+			if err != nil {
+				t.Fatalf("\t\tFailed to parse the OpenAPI 3 document %v %v", err, BallotX)
+			}
+
+			if doc.Swagger == "2.0" && doc.Host == "dev.internal-api.co.uk" && doc.BasePath == "/order-service" {
+				t.Logf("\t\tServers should be converted to host and base path %v", CheckMark)
+			} else {
+				t.Errorf("\t\tServers should be converted to host and base path, got %s%s %v", doc.Host, doc.BasePath, BallotX)
+			}
+
+			if _, ok := doc.Definitions["Order"]; ok {
+				t.Logf("\t\tComponent schemas should be converted to definitions %v", CheckMark)
+			} else {
+				t.Errorf("\t\tComponent schemas should be converted to definitions %v", BallotX)
+			}
+
+			post := doc.Paths.Paths["/orders"].Post
+			body := post.Parameters[len(post.Parameters)-1]
+			if body.In == "body" && body.Schema.Ref.String() == "#/definitions/OrderRequest" {
+				t.Logf("\t\tRequest body should be converted to a body parameter %v", CheckMark)
+			} else {
+				t.Errorf("\t\tRequest body should be converted to a body parameter %v", BallotX)
+			}
+
+			badRequest := post.Responses.StatusCodeResponses[400]
+			if badRequest.Schema != nil && badRequest.Schema.Ref.String() == "#/definitions/HttpExceptionResponse" {
+				t.Logf("\t\tResponse references should be resolved %v", CheckMark)
+			} else {
+				t.Errorf("\t\tResponse references should be resolved %v", BallotX)
+			}
+
+			upload := doc.Paths.Paths["/orders/{orderId}/documents"].Post
+			var formParams []string
+			for _, param := range upload.Parameters {
+				if param.In == "formData" {
+					formParams = append(formParams, param.Name+":"+param.Type)
+				}
+			}
+			if strings.Join(formParams, ",") == "comment:string,file:file" {
+				t.Logf("\t\tMultipart request bodies should be converted to form parameters %v", CheckMark)
+			} else {
+				t.Errorf("\t\tMultipart request bodies should be converted to form parameters, got %v %v", formParams, BallotX)
+			}
+
+			for _, param := range doc.Paths.Paths["/orders"].Get.Parameters {
+				if param.In == "cookie" {
+					t.Errorf("\t\tCookie parameters should be dropped %v", BallotX)
+				}
+			}
+
+			if isPathVisible(doc.Paths.Paths["/admin/orders/{orderId}"]) {
+				t.Errorf("\t\tThe x-publish extension should be carried over %v", BallotX)
+			} else {
+				t.Logf("\t\tThe x-publish extension should be carried over %v", CheckMark)
+			}
+
+			authType, _ := doc.SecurityDefinitions["cognito-authorizer"].Extensions.GetString("x-amazon-apigateway-authtype")
+			if authType == "cognito_user_pools" {
+				t.Logf("\t\tSecurity schemes extensions should be carried over %v", CheckMark)
+			} else {
+				t.Errorf("\t\tSecurity schemes extensions should be carried over %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestParseSwagger_ShouldOnlyRewriteTheComponentReferences(t *testing.T) {
+
+	t.Logf("Given an OpenAPI 3 document whose description mentions a component reference")
+	{
+		data := []byte(`{
+  "openapi": "3.0.1",
+  "info": {"title": "orders", "version": "v1", "description": "See #/components/schemas/Order"},
+  "paths": {},
+  "components": {"schemas": {
+    "Order": {"type": "object", "properties": {"item": { "$ref" : "#/components/schemas/Item" }}},
+    "Item": {"type": "string", "example": "#/components/schemas/Item"}
+  }}
+}`)
+
+		t.Logf("\tWhen parsing the document, only the references should be rewritten")
+		{
+			doc, err := parseSwagger(data)
+			if err != nil {
+				t.Fatalf("\t\tFailed to parse the OpenAPI 3 document %v %v", err, BallotX)
+			}
+			if item := doc.Definitions["Order"].Properties["item"]; item.Ref.String() == "#/definitions/Item" {
+				t.Logf("\t\tThe reference should be rewritten %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe reference should be rewritten, got %s %v", item.Ref.String(), BallotX)
+			}
+			if doc.Info.Description == "See #/components/schemas/Order" && doc.Definitions["Item"].Example == "#/components/schemas/Item" {
+				t.Logf("\t\tThe description and example should be left as is %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe description and example should be left as is, got %q %v %v", doc.Info.Description, doc.Definitions["Item"].Example, BallotX)
+			}
+		}
+	}
+}
+
+func TestSwaggerClient_RenderSwaggerShouldAddAWSExtensionsToOpenAPI3Document(t *testing.T) {
+
+	t.Logf("Given we read an OpenAPI 3 document")
+	{
+		t.Logf("\tWhen calling RenderSwagger method, it should have added the AWS extension")
+		{
+			data, _ := ioutil.ReadFile("../data/openapi3.json")
+			doc, _ := parseSwagger(data)
+
+			client := NewSwaggerClient("order-service")
+			renderSwagger, err := client.RenderSwagger(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the OpenAPI 3 document %v %v", err, BallotX)
+			}
+
+			var dataResult swg.Swagger
+			decoder := json.NewDecoder(bytes.NewReader(renderSwagger))
+			decoder.Decode(&dataResult)
+
+			if _, ok := dataResult.Paths.Paths["/admin/orders/{orderId}"]; !ok {
+				t.Logf("\t\tPaths with x-publish set to false should not be published %v", CheckMark)
+			} else {
+				t.Errorf("\t\tPaths with x-publish set to false should not be published %v", BallotX)
+			}
+
+			if strings.Contains(string(renderSwagger), "https://dev.internal-api.co.uk/order-service/orders") {
+				t.Logf("\t\tThe integration uri should be built from the servers block %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe integration uri should be built from the servers block %v", BallotX)
+			}
+
+			if strings.Contains(string(renderSwagger), "components") {
+				t.Errorf("\t\tRendered swagger should not reference components %v", BallotX)
+			} else {
+				t.Logf("\t\tRendered swagger should not reference components %v", CheckMark)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package swagger
+
+import (
+	"encoding/json"
+	"net/http"
+
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// AnyMethod the API Gateway method matching all the http methods
+	AnyMethod          = "ANY"
+	AnyMethodExtension = "x-amazon-apigateway-any-method"
+)
+
+// Operation an operation of a path along with its http method
+type Operation struct {
+	Method string
+	*swg.Operation
+}
+
+// Operations - Function
+// Returns all the operations of the path, including the `x-amazon-apigateway-any-method` one, in a stable order
+func Operations(item swg.PathItem) []Operation {
+	var operations []Operation
+	for _, operation := range []Operation{
+		{http.MethodGet, item.Get},
+		{http.MethodPut, item.Put},
+		{http.MethodPost, item.Post},
+		{http.MethodDelete, item.Delete},
+		{http.MethodOptions, item.Options},
+		{http.MethodHead, item.Head},
+		{http.MethodPatch, item.Patch},
+		{AnyMethod, anyMethod(item)},
+	} {
+		if operation.Operation != nil {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// anyMethod decodes the operation held in the `x-amazon-apigateway-any-method` extension of the path
+func anyMethod(item swg.PathItem) *swg.Operation {
+	value, ok := item.Extensions[AnyMethodExtension]
+	if !ok {
+		return nil
+	}
+	if op, ok := value.(*swg.Operation); ok {
+		return op
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var op swg.Operation
+	if err := json.Unmarshal(data, &op); err != nil {
+		return nil
+	}
+	return &op
+}
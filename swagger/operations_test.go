@@ -0,0 +1,215 @@
+package swagger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+func readAccountSwagger(t *testing.T) swg.Swagger {
+	var doc swg.Swagger
+	data, _ := ioutil.ReadFile("../data/swagger.json")
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("\t\tFailed to read the swagger document %v %v", err, BallotX)
+	}
+	return doc
+}
+
+func integrationOf(op *swg.Operation) model.AWSAPIGatewayIntegration {
+	var integration model.AWSAPIGatewayIntegration
+	data, _ := json.Marshal(op.Extensions[AWSExtensionsFieldName])
+	json.Unmarshal(data, &integration)
+	return integration
+}
+
+func TestRenderSwagger_ShouldIntegrateHeadAndAnyMethods(t *testing.T) {
+
+	t.Logf("Given the vanilla swagger has HEAD and x-amazon-apigateway-any-method operations")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		item.Head = swg.NewOperation("headAccount")
+		item.Head.Responses = &swg.Responses{}
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+
+		item = doc.Paths.Paths["/admin/accounts"]
+		item.AddExtension(AnyMethodExtension, map[string]interface{}{"operationId": "anyAdmin", "responses": map[string]interface{}{}})
+		doc.Paths.Paths["/admin/accounts"] = item
+
+		t.Logf("\tWhen rendering the swagger, every operation should get an integration")
+		{
+			data, report, err := NewSwaggerClient("account-service").RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			head := rendered.Paths.Paths["/accounts/{accountId}"].Head
+			if head != nil && integrationOf(head).HTTPMethod == "HEAD" {
+				t.Logf("\t\tThe HEAD operation should be integrated %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe HEAD operation should be integrated %v", BallotX)
+			}
+
+			operations := Operations(rendered.Paths.Paths["/admin/accounts"])
+			any := operations[len(operations)-1]
+			if any.Method == AnyMethod && integrationOf(any.Operation).HTTPMethod == AnyMethod {
+				t.Logf("\t\tThe ANY operation should be integrated %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe ANY operation should be integrated, got %v %v", operations, BallotX)
+			}
+
+			found := 0
+			for _, integration := range report.Integrations {
+				if integration.Method == "HEAD" || integration.Method == AnyMethod {
+					found++
+				}
+			}
+			if found == 2 {
+				t.Logf("\t\tThe report should list the HEAD and ANY integrations %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe report should list the HEAD and ANY integrations, got %d %v", found, BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldIntegrateAnAnyMethodWithoutResponses(t *testing.T) {
+
+	t.Logf("Given a x-amazon-apigateway-any-method operation declaring no responses")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/admin/accounts"]
+		item.AddExtension(AnyMethodExtension, map[string]interface{}{"operationId": "anyAdmin"})
+		doc.Paths.Paths["/admin/accounts"] = item
+
+		t.Logf("\tWhen rendering the swagger with cors enabled, the operation should be integrated")
+		{
+			options := OptionsFromEnv()
+			options.CorsEnabled = true
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			if any := anyMethod(rendered.Paths.Paths["/admin/accounts"]); any != nil && integrationOf(any).HTTPMethod == AnyMethod {
+				t.Logf("\t\tThe ANY operation should be integrated %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe ANY operation should be integrated %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldAddTheGreedyProxyRouteWhenEnabled(t *testing.T) {
+
+	t.Logf("Given the proxy is enabled")
+	{
+		options := OptionsFromEnv()
+		options.ProxyEnabled = true
+		options.EndpointURL = "account.internal/account-service"
+
+		t.Logf("\tWhen rendering the swagger, a greedy ANY /{proxy+} passthrough should be added")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(readAccountSwagger(t))
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			proxy, ok := rendered.Paths.Paths[ProxyPath]
+			if !ok || anyMethod(proxy) == nil {
+				t.Fatalf("\t\tThe greedy route should be added %v", BallotX)
+			}
+			integration := integrationOf(anyMethod(proxy))
+			if integration.IntegrationType == "http_proxy" && integration.URI == "http://account.internal/account-service/{proxy}" {
+				t.Logf("\t\tThe greedy route should pass the requests through to the backend %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe greedy route should pass the requests through to the backend, got %+v %v", integration, BallotX)
+			}
+
+			if proxy.Options != nil {
+				t.Logf("\t\tThe greedy route should support cors %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe greedy route should support cors %v", BallotX)
+			}
+
+			if _, ok := rendered.Paths.Paths["/accounts/{accountId}"]; ok {
+				t.Logf("\t\tThe routes of the document should still be published %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe routes of the document should still be published %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldGuardTheProxyWithTheProxyAuthorizer(t *testing.T) {
+
+	t.Logf("Given the proxy is enabled with named authorizers only")
+	{
+		options := OptionsFromEnv()
+		options.AuthType = ""
+		options.AuthName = ""
+		options.ProxyEnabled = true
+		options.Authorizers = []Authorizer{
+			{Name: "partner", Type: CognitoAuth, ProviderARNs: []string{"arn:aws:cognito-idp:eu-west-1:123456789012:userpool/eu-west-1_Partner"}},
+		}
+
+		t.Logf("\tWhen validating the options without a proxy authorizer, the unguarded proxy should be reported")
+		{
+			if problems := strings.Join(options.Validate(), "\n"); strings.Contains(problems, ProxyAuthorizer) {
+				t.Logf("\t\tThe missing proxy authorizer should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe missing proxy authorizer should be reported, got %v %v", problems, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen rendering the swagger with the proxy authorizer and the api key required, the proxy should be guarded by both")
+		{
+			options.ProxyAuthorizer = "partner"
+			options.ProxyAPIKeyRequired = true
+			doc := readAccountSwagger(t)
+			for _, item := range doc.Paths.Paths {
+				for _, operation := range Operations(item) {
+					operation.AddExtension(AuthorizerExtension, "partner")
+				}
+			}
+			data, report, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			expected := []map[string][]string{{"partner": {}, apiKeySchemeName: {}}}
+			if proxy := anyMethod(rendered.Paths.Paths[ProxyPath]); proxy != nil && reflect.DeepEqual(proxy.Security, expected) {
+				t.Logf("\t\tThe proxy should require the partner authorizer and the api key %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe proxy should require the partner authorizer and the api key, got %v %v", proxy, BallotX)
+			}
+			if _, ok := rendered.SecurityDefinitions[apiKeySchemeName]; ok {
+				t.Logf("\t\tThe api key should be defined %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe api key should be defined %v", BallotX)
+			}
+			secured := false
+			for _, operation := range report.Secured {
+				secured = secured || operation == AnyMethod+" "+ProxyPath
+			}
+			if secured {
+				t.Logf("\t\tThe proxy should be reported secured %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe proxy should be reported secured, got %v %v", report.Secured, BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package swagger
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	log "github.com/sirupsen/logrus"
+)
+
+const VPCLinkConnectionType = "VPC_LINK"
+
+// Options the settings used to render a vanilla swagger document into one that can be published to AWS api gateway
+type Options struct {
+	APIGatewayName string
+	EndpointURL    string
+	// EndpointScheme and EndpointPort are used when the endpoint url does not give them
+	EndpointScheme string
+	EndpointPort   string
+	ConnectionType string
+	VPCLinkID      string
+	AuthType       string
+	AuthName       string
+	AuthURL        string
+	// Authorizer the lambda authorizer of the custom auth
+	Authorizer LambdaAuthorizer
+	// Authorizers the authorizers the operations select besides the default one above
+	Authorizers []Authorizer
+	// Policy the resource policy of the API, none when empty
+	Policy ResourcePolicy
+	// AuthProviderARNs the arns of the user pools of the cognito authorizer
+	AuthProviderARNs []string
+	CorsEnabled      bool
+	Cors             Cors
+	ProxyEnabled     bool
+	// ProxyAuthorizer the name of the authorizer guarding the greedy route, the default authorizer when empty
+	ProxyAuthorizer     string
+	ProxyAPIKeyRequired bool
+	// StageVariablesEnabled the integrations reference the endpoint url and vpc link id stage variables rather than
+	// their values, StageVariablePrefix is prepended to their names
+	StageVariablesEnabled bool
+	StageVariablePrefix   string
+	// RequestValidator the validator of the operations without a x-amazon-apigateway-request-validator extension
+	RequestValidator string
+	// ResponseMappings the integration responses of every operation, DefaultResponseMappings when nil, ResponseHeaders
+	// the headers passed through on all of them
+	ResponseMappings []ResponseMapping
+	ResponseHeaders  []string
+	// GatewayResponses the responses API Gateway returns when it rejects a request, keyed by type, exp: UNAUTHORIZED
+	GatewayResponses map[string]GatewayResponse
+	Region           string
+	AccountID        string
+	// Integrations the backends of the routes (`POST /orders`) or paths (`/orders`) not using the http integration
+	Integrations  map[string]Integration
+	CustomHeaders []string
+	Fetch         FetchOptions
+}
+
+// OptionsFromEnv - Function
+// Reads the render options from the environment variables
+func OptionsFromEnv() Options {
+	// cors is enabled by the presence of the environment variable, as it always was
+	_, corsEnabled := os.LookupEnv(CorsEnabled)
+	var proxyEnabled, proxyAPIKeyRequired, stageVariablesEnabled bool
+	fetch, problems := FetchOptionsFromEnv()
+	problems = append(problems, LookupBool(ProxyEnabled, &proxyEnabled)...)
+	problems = append(problems, LookupBool(ProxyAPIKeyRequired, &proxyAPIKeyRequired)...)
+	problems = append(problems, LookupBool(StageVariablesEnabled, &stageVariablesEnabled)...)
+	var cors Cors
+	problems = append(problems, cors.ApplyEnv()...)
+	var authorizer LambdaAuthorizer
+	problems = append(problems, authorizer.ApplyEnv()...)
+	for _, problem := range problems {
+		log.Warn(problem)
+	}
+	return Options{
+		APIGatewayName:        os.Getenv(ApiGwName),
+		EndpointURL:           os.Getenv(EndpointUrl),
+		EndpointScheme:        os.Getenv(EndpointScheme),
+		EndpointPort:          os.Getenv(EndpointPort),
+		ConnectionType:        os.Getenv(ConnectionType),
+		VPCLinkID:             os.Getenv(VPCLinkID),
+		AuthType:              os.Getenv(AuthType),
+		AuthName:              os.Getenv(AuthName),
+		AuthURL:               os.Getenv(AuthUrl),
+		Authorizer:            authorizer,
+		AuthProviderARNs:      SplitList(os.Getenv(AuthProviderARNs)),
+		CorsEnabled:           corsEnabled,
+		Cors:                  cors,
+		ProxyEnabled:          proxyEnabled,
+		ProxyAuthorizer:       os.Getenv(ProxyAuthorizer),
+		ProxyAPIKeyRequired:   proxyAPIKeyRequired,
+		StageVariablesEnabled: stageVariablesEnabled,
+		RequestValidator:      os.Getenv(RequestValidator),
+		ResponseHeaders:       SplitList(os.Getenv(ResponseHeaders)),
+		Region:                os.Getenv(AWSRegion),
+		AccountID:             os.Getenv(AWSAccountID),
+		CustomHeaders:         SplitList(os.Getenv(CustomHeaders)),
+		Fetch:                 fetch,
+	}
+}
+
+// Validate returns all the problems found in the options rather than stopping at the first one
+func (o Options) Validate() []string {
+	var problems []string
+	if o.APIGatewayName == "" {
+		problems = append(problems, fmt.Sprintf("the api gateway name (--api-gateway-name or %s) is required", ApiGwName))
+	}
+
+	problems = append(problems, o.validateEndpoint()...)
+	problems = append(problems, validateResponseMappings(o.ResponseMappings)...)
+	problems = append(problems, o.Cors.Validate()...)
+	problems = append(problems, validateGatewayResponses(o.GatewayResponses)...)
+	if !isValidator(strings.ToLower(o.RequestValidator)) {
+		problems = append(problems, fmt.Sprintf("unsupported request validator (%s) %q, expected one of %s", RequestValidator, o.RequestValidator, validatorNames()))
+	}
+
+	switch strings.ToUpper(o.ConnectionType) {
+	case "", PublicConnectionType:
+	case VPCLinkConnectionType:
+		if o.VPCLinkID == "" {
+			problems = append(problems, fmt.Sprintf("the vpc link id (%s) is required for the %s connection type", VPCLinkID, VPCLinkConnectionType))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported connection type (%s) %q, expected one of %s, %s",
+			ConnectionType, o.ConnectionType, PublicConnectionType, VPCLinkConnectionType))
+	}
+
+	problems = append(problems, o.validateAuth()...)
+	problems = append(problems, o.validateProxy()...)
+	problems = append(problems, o.Policy.Validate()...)
+	problems = append(problems, validateIntegrations(o.Integrations, o.AccountID)...)
+	return append(problems, o.Fetch.Validate()...)
+}
+
+// region the region of the lambda functions and aws services integrated, defaults to eu-west-1
+func (o Options) region() string {
+	if o.Region == "" {
+		return endpoints.EuWest1RegionID
+	}
+	return o.Region
+}
+
+func (o Options) isCustomAuth() bool {
+	return strings.ToLower(o.AuthType) == strings.ToLower(CustomAuth)
+}
+
+// LookupBool sets the value from the environment variable when it is set, a value other than true or false is
+// reported as a problem
+func LookupBool(key string, value *bool) []string {
+	envValue, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(envValue)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid value (%s) %q, expected true or false", key, envValue)}
+	}
+	*value = enabled
+	return nil
+}
+
+// SplitList splits a comma separated list, blank entries are dropped
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
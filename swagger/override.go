@@ -0,0 +1,119 @@
+package swagger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// OverrideExtension the operation or path extension overriding the settings of the generated integration
+	OverrideExtension = "x-apigw-pub-integration"
+
+	minTimeoutInMillis = 50
+	maxTimeoutInMillis = 29000
+)
+
+var passthroughBehaviors = []string{"when_no_match", "when_no_templates", "never"}
+
+// IntegrationOverride the settings of the generated integration overridden with the `x-apigw-pub-integration`
+// extension, the extension of the path is applied first then the one of the operation
+type IntegrationOverride struct {
+	// URI the backend uri, a uri starting with `/` is appended to the backend url of the service
+	URI                 string `json:"uri"`
+	HTTPMethod          string `json:"httpMethod"`
+	ConnectionType      string `json:"connectionType"`
+	ConnectionID        string `json:"connectionId"`
+	TimeoutInMillis     int    `json:"timeoutInMillis"`
+	PassthroughBehavior string `json:"passthroughBehavior"`
+	// RequestParameters added to the mapped parameters, a blank value removes the mapping
+	RequestParameters  map[string]string `json:"requestParameters"`
+	CacheKeyParameters []string          `json:"cacheKeyParameters"`
+	CacheNamespace     string            `json:"cacheNamespace"`
+}
+
+// resolveOverrides returns the overrides of the path and of the operation, in the order they are applied
+func resolveOverrides(path swg.PathItem, operation Operation, key string) ([]IntegrationOverride, error) {
+	var overrides []IntegrationOverride
+	for _, extensions := range []swg.Extensions{path.Extensions, operation.Extensions} {
+		value, ok := extensions[OverrideExtension]
+		if !ok {
+			continue
+		}
+		var override IntegrationOverride
+		if err := decodeExtension(value, &override); err != nil {
+			return nil, fmt.Errorf("invalid %s extension on %s %s: %s", OverrideExtension, operation.Method, key, err)
+		}
+		if problems := override.Validate(); len(problems) > 0 {
+			return nil, fmt.Errorf("invalid %s extension on %s %s: %s", OverrideExtension, operation.Method, key, strings.Join(problems, "; "))
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// Validate returns the problems of the override
+func (o IntegrationOverride) Validate() []string {
+	var problems []string
+	if o.TimeoutInMillis != 0 && (o.TimeoutInMillis < minTimeoutInMillis || o.TimeoutInMillis > maxTimeoutInMillis) {
+		problems = append(problems, fmt.Sprintf("the timeoutInMillis %d must be between %d and %d", o.TimeoutInMillis, minTimeoutInMillis, maxTimeoutInMillis))
+	}
+	if o.PassthroughBehavior != "" && !contains(passthroughBehaviors, strings.ToLower(o.PassthroughBehavior)) {
+		problems = append(problems, fmt.Sprintf("unsupported passthroughBehavior %q, expected one of %s", o.PassthroughBehavior, strings.Join(passthroughBehaviors, ", ")))
+	}
+	if strings.ToUpper(o.ConnectionType) == VPCLinkConnectionType && o.ConnectionID == "" {
+		problems = append(problems, fmt.Sprintf("the connectionId is required for the %s connection type", VPCLinkConnectionType))
+	}
+	return problems
+}
+
+// apply merges the override into the generated integration
+func (o IntegrationOverride) apply(extension *model.AWSAPIGatewayIntegration, endpointUrl string) {
+	if strings.HasPrefix(o.URI, "/") {
+		extension.URI = endpointUrl + o.URI
+	} else if o.URI != "" {
+		extension.URI = o.URI
+	}
+	if o.HTTPMethod != "" {
+		extension.HTTPMethod = strings.ToUpper(o.HTTPMethod)
+	}
+	if o.ConnectionType != "" {
+		extension.ConnectionType = strings.ToUpper(o.ConnectionType)
+		extension.ConnectionID = o.ConnectionID
+	} else if o.ConnectionID != "" {
+		extension.ConnectionID = o.ConnectionID
+	}
+	if o.TimeoutInMillis != 0 {
+		extension.TimeoutInMillis = o.TimeoutInMillis
+	}
+	if o.PassthroughBehavior != "" {
+		extension.PassthroughBehavior = strings.ToLower(o.PassthroughBehavior)
+	}
+	for name, value := range o.RequestParameters {
+		if extension.RequestParameters == nil {
+			extension.RequestParameters = map[string]string{}
+		}
+		if value == "" {
+			delete(extension.RequestParameters, name)
+		} else {
+			extension.RequestParameters[name] = value
+		}
+	}
+	if len(o.CacheKeyParameters) > 0 {
+		extension.CacheKeyParameters = o.CacheKeyParameters
+	}
+	if o.CacheNamespace != "" {
+		extension.CacheNamespace = o.CacheNamespace
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
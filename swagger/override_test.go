@@ -0,0 +1,114 @@
+package swagger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldApplyTheIntegrationOverrides(t *testing.T) {
+
+	t.Logf("Given the vanilla swagger has x-apigw-pub-integration extensions on a path and on its operation")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		item.AddExtension(OverrideExtension, map[string]interface{}{"timeoutInMillis": 29000, "uri": "/v1/accounts/{accountId}"})
+		item.Get.AddExtension(OverrideExtension, map[string]interface{}{
+			"uri":                "/v2/accounts/{accountId}",
+			"connectionType":     "vpc_link",
+			"connectionId":       "abc123",
+			"requestParameters":  map[string]string{"integration.request.header.X-Tenant": "method.request.header.X-Tenant"},
+			"cacheKeyParameters": []string{"method.request.path.accountId"},
+		})
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+
+		options := OptionsFromEnv()
+		options.EndpointURL = "account.internal"
+
+		t.Logf("\tWhen rendering the swagger, the overrides should be merged into the integration")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			path := rendered.Paths.Paths["/accounts/{accountId}"]
+			integration := integrationOf(path.Get)
+			if integration.URI == "http://account.internal/v2/accounts/{accountId}" && integration.ConnectionType == VPCLinkConnectionType &&
+				integration.ConnectionID == "abc123" && integration.TimeoutInMillis == 29000 &&
+				integration.RequestParameters["integration.request.header.X-Tenant"] == "method.request.header.X-Tenant" &&
+				len(integration.CacheKeyParameters) == 1 {
+				t.Logf("\t\tThe operation override should be applied over the path one %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe operation override should be applied over the path one, got %+v %v", integration, BallotX)
+			}
+
+			if _, ok := path.Extensions[OverrideExtension]; !ok {
+				if _, ok := path.Get.Extensions[OverrideExtension]; !ok {
+					t.Logf("\t\tThe extension should be removed from the rendered swagger %v", CheckMark)
+					return
+				}
+			}
+			t.Errorf("\t\tThe extension should be removed from the rendered swagger %v", BallotX)
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldRejectInvalidIntegrationOverrides(t *testing.T) {
+
+	t.Logf("Given an x-apigw-pub-integration extension with an invalid timeout and a VPC link without id")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		item.Get.AddExtension(OverrideExtension, map[string]interface{}{"timeoutInMillis": 60000, "connectionType": "VPC_LINK"})
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+
+		t.Logf("\tWhen rendering the swagger, an error should be returned")
+		{
+			if _, _, err := NewSwaggerClient("account-service").RenderSwaggerWithReport(doc); err != nil {
+				t.Logf("\t\tThe invalid override should be rejected: %v %v", err, CheckMark)
+			} else {
+				t.Errorf("\t\tThe invalid override should be rejected %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldRejectTheUnknownFieldsOfTheExtensions(t *testing.T) {
+
+	t.Logf("Given extensions with a misspelled field")
+	{
+		cases := []struct {
+			extension string
+			value     map[string]interface{}
+		}{
+			{OverrideExtension, map[string]interface{}{"timeoutInMilis": 5000}},
+			{IntegrationExtension, map[string]interface{}{"type": "http_proxy", "connectionTyp": "VPC_LINK"}},
+			{CorsExtension, map[string]interface{}{"allowOrigin": []string{"https://app.example.com"}}},
+		}
+		for _, c := range cases {
+			doc := readAccountSwagger(t)
+			item := doc.Paths.Paths["/accounts/{accountId}"]
+			if c.extension == CorsExtension {
+				item.AddExtension(c.extension, c.value)
+			} else {
+				item.Get.AddExtension(c.extension, c.value)
+			}
+			doc.Paths.Paths["/accounts/{accountId}"] = item
+
+			t.Logf("\tWhen rendering the swagger with the %s extension, the unknown field should be reported with the route", c.extension)
+			{
+				_, _, err := NewSwaggerClient("account-service").RenderSwaggerWithReport(doc)
+				if err != nil && strings.Contains(err.Error(), "/accounts/{accountId}") && strings.Contains(err.Error(), "unknown field") {
+					t.Logf("\t\tThe unknown field should be rejected: %v %v", err, CheckMark)
+				} else {
+					t.Errorf("\t\tThe unknown field should be rejected, got %v %v", err, BallotX)
+				}
+			}
+		}
+	}
+}
@@ -1,13 +1,9 @@
 package swagger
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/akhettar/apigw-pub/model"
-	"github.com/akhettar/apigw-pub/utils"
 	"net/http"
-	"os"
-	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -49,142 +45,184 @@ func init() {
 // 3. Add AWS API Gateway integration extensions to the vanilla swagger doc
 type SwaggerParser struct {
 	swaggerUrl string
+	options    Options
 }
 
 // NewSwaggerClient - Function
+// Creates a swagger client with the render options read from the environment variables
 func NewSwaggerClient(swaggerUrl string) SwaggerParser {
-	return SwaggerParser{swaggerUrl: swaggerUrl}
+	return NewSwaggerClientWithOptions(swaggerUrl, OptionsFromEnv())
+}
+
+// NewSwaggerClientWithOptions - Function
+func NewSwaggerClientWithOptions(swaggerUrl string, options Options) SwaggerParser {
+	return SwaggerParser{swaggerUrl: swaggerUrl, options: options}
 }
 
 // FetchSwagger - Function
-// Fetches Swagger for a given service from the its deployed environment
+// Fetches Swagger for a given service from the its deployed environment, a local file or stdin. Yaml documents are
+// accepted as well as json ones
 func (client SwaggerParser) FetchSwagger() (swg.Swagger, error) {
-
-	log.WithFields(log.Fields{"Swagger URL": client.swaggerUrl}).Info("Fetching vanilla swagger from the given swagger url")
-
-	resp, err := http.Get(client.swaggerUrl)
+	var doc swg.Swagger
+	data, err := client.readSource()
 	if err != nil {
-		log.Errorf("Error when getting Swagger docs: %s", err)
+		log.Errorf("Failed to read swagger doc from %s", client.swaggerUrl)
+		return doc, err
 	}
 
-	defer resp.Body.Close()
-
-	var data swg.Swagger
-
+	data, err = toJSON(data)
 	if err != nil {
-		log.Errorf("Failed to fetch swagger doc from %s", client.swaggerUrl)
-		return data, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Errorf("Got error from the server with http code %d", resp.StatusCode)
-		return data, fmt.Errorf("Got error from the server with http code %d", resp.StatusCode)
+		return doc, err
 	}
 
-	// parsing the swagger doc
-	decoder := json.NewDecoder(resp.Body)
-	err = decoder.Decode(&data)
-	return data, err
+	// parsing the swagger doc, OpenAPI 3.x documents are converted to swagger 2.0
+	return parseSwagger(data)
 }
 
 // RenderSwagger - Function
 // Renders the vanilla swagger document into one that can be published to AWS api gateway
 func (client SwaggerParser) RenderSwagger(doc swg.Swagger) ([]byte, error) {
+	json, _, err := client.RenderSwaggerWithReport(doc)
+	return json, err
+}
 
-	endpointUrl := utils.FetchEnvVar(EndpointUrl, fmt.Sprintf("%s%s", doc.Host, doc.BasePath))
+// RenderSwaggerWithReport - Function
+// Renders the vanilla swagger document and reports which paths were published, skipped and secured along with the
+// generated integrations
+func (client SwaggerParser) RenderSwaggerWithReport(doc swg.Swagger) ([]byte, RenderReport, error) {
+	swaggerWithExtensions, report, err := client.render(doc)
+	if err != nil {
+		return nil, report, err
+	}
+	json, err := swaggerWithExtensions.MarshalJSON()
+	return json, report, err
+}
+
+// render adds the AWS extensions to the vanilla swagger document
+func (client SwaggerParser) render(doc swg.Swagger) (swg.Swagger, RenderReport, error) {
+	var report RenderReport
+	options := client.options
+	if problems := options.Validate(); len(problems) > 0 {
+		return swg.Swagger{}, report, fmt.Errorf("invalid render options: %s", strings.Join(problems, "; "))
+	}
+
+	if options.StageVariablesEnabled && options.EndpointURL == "" {
+		return swg.Swagger{}, report, fmt.Errorf("the endpoint url (%s) is required to set the %s stage variable", EndpointUrl, options.stageVariable(EndpointURLVariable))
+	}
+	endpointUrl := backendURL(doc, options)
 
 	swaggerWithExtensions := swg.Swagger{
 		SwaggerProps: doc.SwaggerProps,
 	}
 
 	// Setting the swagger Tile to that of the asto api gateway to avoid the overriding of the api gateway name by the REST API import call
-	swaggerWithExtensions.Info.Title = utils.RetrieveEnvVar(ApiGwName)
+	swaggerWithExtensions.Info.Title = options.APIGatewayName
 	for key, value := range doc.Paths.Paths {
 		if isPathVisible(value) {
 			log.WithFields(log.Fields{"key": key}).Info(" Publishing ✅")
+			report.Published = append(report.Published, key)
 		} else {
 			log.WithFields(log.Fields{"key": key}).Info(" Skipping Publish ❌")
+			report.Skipped = append(report.Skipped, key)
 			delete(swaggerWithExtensions.Paths.Paths, key)
 		}
 	}
 
-	// Add custom authorization if set
-	if strings.ToLower(os.Getenv(AuthType)) == strings.ToLower(CustomAuth) {
-		swaggerWithExtensions.SecurityDefinitions = buildCustomAuthorizerBlock()
+	// the operations are secured with the authorizers only
+	if len(options.authorizers()) > 0 {
+		swaggerWithExtensions.SecurityDefinitions = securityDefinitions(options)
+		swaggerWithExtensions.Security = nil
 	}
 
 	// Apply filters
 	applyFilters(&swaggerWithExtensions)
 
 	// adding aws extension for all the defined operations for a given endpoint
+	validated := false
+	keyed := false
 	for key, path := range doc.Paths.Paths {
-		if path.Get != nil {
-			addAWSExtensions(path.Get, key, http.MethodGet, endpointUrl, isSecurityEnabled(path))
-			addOperationCORSHeaders(path.Get)
-			renameNonAlphanumericReference(path.Get)
+		pathOptions, err := resolveCors(path, key, options)
+		if err != nil {
+			return swg.Swagger{}, report, err
 		}
-
-		if path.Put != nil {
-			addAWSExtensions(path.Put, key, http.MethodPut, endpointUrl, isSecurityEnabled(path))
-			addOperationCORSHeaders(path.Put)
-			renameNonAlphanumericReference(path.Put)
-		}
-
-		if path.Delete != nil {
-			addAWSExtensions(path.Delete, key, http.MethodDelete, endpointUrl, isSecurityEnabled(path))
-			addOperationCORSHeaders(path.Delete)
-			renameNonAlphanumericReference(path.Delete)
-		}
-
-		if path.Patch != nil {
-			addAWSExtensions(path.Patch, key, http.MethodPatch, endpointUrl, isSecurityEnabled(path))
-			addOperationCORSHeaders(path.Patch)
-			renameNonAlphanumericReference(path.Patch)
+		for _, operation := range Operations(path) {
+			// replaced by the cors support below
+			if operation.Method == http.MethodOptions && pathOptions.CorsEnabled {
+				continue
+			}
+			auth, err := resolveAuthorization(path, operation, key, doc.Security, options)
+			if err != nil {
+				return swg.Swagger{}, report, err
+			}
+			backend, err := resolveIntegration(path, operation, key, options)
+			if err != nil {
+				return swg.Swagger{}, report, err
+			}
+			overrides, err := resolveOverrides(path, operation, key)
+			if err != nil {
+				return swg.Swagger{}, report, err
+			}
+			validator, err := resolveValidator(path, operation, key, options)
+			if err != nil {
+				return swg.Swagger{}, report, err
+			}
+			integration := addAWSExtensions(operation.Operation, key, operation.Method, endpointUrl, auth, backend, overrides, pathOptions)
+			if isAPIKeyRequired(path, operation) {
+				requireAPIKey(operation.Operation)
+				keyed = keyed || isPathVisible(path)
+			}
+			delete(operation.Extensions, APIKeyRequiredExtension)
+			setValidator(operation.Operation, validator)
+			validated = validated || isPathVisible(path) && validator != "" && validator != NoValidator
+			report.addOperation(key, operation.Method, integration, auth.secured())
+			addOperationCORSHeaders(operation.Operation, pathOptions)
+			renameNonAlphanumericReference(operation.Operation)
+			if operation.Method == AnyMethod {
+				path.Extensions[AnyMethodExtension] = operation.Operation
+			}
 		}
-
-		if path.Post != nil {
-			addAWSExtensions(path.Post, key, http.MethodPost, endpointUrl, isSecurityEnabled(path))
-			addOperationCORSHeaders(path.Post)
-			renameNonAlphanumericReference(path.Post)
+		delete(path.Extensions, IntegrationExtension)
+		delete(path.Extensions, OverrideExtension)
+		delete(path.Extensions, RequestValidatorExtension)
+		delete(path.Extensions, CorsExtension)
+		delete(path.Extensions, AuthorizerExtension)
+		delete(path.Extensions, APIKeyRequiredExtension)
+
+		// cors enabled? the preflight operation is added to the published paths only
+		if _, published := swaggerWithExtensions.Paths.Paths[key]; published && pathOptions.CorsEnabled {
+			methods := pathOptions.Cors.allowMethods(path)
+			path.Options = swg.NewOperation("add_cors")
+			addOptionsCORSSupport(path.Options, key, methods, pathOptions)
+			swaggerWithExtensions.Paths.Paths[key] = path
 		}
+	}
 
-		// cors enabled?
-		_, corsEnabled := os.LookupEnv(CorsEnabled)
-		if corsEnabled {
-			pathPointer := doc.Paths.Paths[key]
-			pathPointer.Options = swg.NewOperation("add_cors")
-			addOptionsCORSSupport(pathPointer.Options, key, http.MethodOptions)
-			doc.Paths.Paths[key] = pathPointer
-		}
+	addRequestValidators(&swaggerWithExtensions, validated)
+	if keyed {
+		addAPIKeyDefinition(&swaggerWithExtensions)
 	}
-	json, err := swaggerWithExtensions.MarshalJSON()
-	return json, err
-}
+	addGatewayResponses(&swaggerWithExtensions, options)
+	addResourcePolicy(&swaggerWithExtensions, options)
 
-func buildCustomAuthorizerBlock() map[string]*swg.SecurityScheme {
-	secWith := utils.RetrieveEnvVar(AuthName)
-	return map[string]*swg.SecurityScheme{
-		secWith: {SecuritySchemeProps: swg.SecuritySchemeProps{
-			Type: "apiKey",
-			Name: "Authorization",
-			In:   "header",
-		},
-			VendorExtensible: swg.VendorExtensible{
-				Extensions: map[string]interface{}{
-					"x-amazon-apigateway-authtype": "custom",
-					"x-amazon-apigateway-authorizer": map[string]interface{}{
-						"authorizerUri":                utils.RetrieveEnvVar(AuthUrl),
-						"authorizerResultTtlInSeconds": 0,
-						"type":                         "token",
-					},
-				},
-			},
-		},
+	// greedy passthrough of all the routes not defined in the document
+	if options.ProxyEnabled {
+		if _, ok := swaggerWithExtensions.Paths.Paths[ProxyPath]; ok {
+			return swg.Swagger{}, report, fmt.Errorf("the greedy path %s is already defined in the document", ProxyPath)
+		}
+		integration, secured := addProxyRoute(&swaggerWithExtensions, endpointUrl, options)
+		report.Published = append(report.Published, ProxyPath)
+		report.addOperation(ProxyPath, AnyMethod, integration, secured)
 	}
+	report.sort()
+	return swaggerWithExtensions, report, nil
 }
 
 func renameNonAlphanumericReference(operation *swg.Operation) {
-	for _, response := range operation.Responses.ResponsesProps.StatusCodeResponses {
+	var responses map[int]swg.Response
+	if operation.Responses != nil {
+		responses = operation.Responses.StatusCodeResponses
+	}
+	for _, response := range responses {
 		if response.ResponseProps.Schema != nil {
 			ref := response.ResponseProps.Schema.SchemaProps.Ref.Ref
 			url := ref.GetURL()
@@ -203,8 +241,8 @@ func renameNonAlphanumericReference(operation *swg.Operation) {
 	}
 }
 
-// Adds Swagger Extensions
-func addAWSExtensions(op *swg.Operation, key string, method string, endpointUrl string, securityEnabled bool) {
+// Adds Swagger Extensions and returns the generated integration
+func addAWSExtensions(op *swg.Operation, key string, method string, endpointUrl string, auth authorization, backend Integration, overrides []IntegrationOverride, options Options) model.AWSAPIGatewayIntegration {
 	requestParams := make(map[string]string)
 	for _, param := range op.Parameters {
 		if param.In == "path" {
@@ -222,14 +260,10 @@ func addAWSExtensions(op *swg.Operation, key string, method string, endpointUrl
 	}
 
 	// set all the headers
-	customHeaders, ok := os.LookupEnv(CustomHeaders)
-	if ok {
-		for _, header := range strings.Split(customHeaders, ",") {
-			name := strings.TrimSpace(header)
-			addHeaderParameter(op, name, "header", true, name)
-			requestParams[fmt.Sprintf("integration.request.header.%s", name)] =
-				fmt.Sprintf("method.request.header.%s", name)
-		}
+	for _, name := range options.CustomHeaders {
+		addHeaderParameter(op, name, "header", true, name)
+		requestParams[fmt.Sprintf("integration.request.header.%s", name)] =
+			fmt.Sprintf("method.request.header.%s", name)
 	}
 
 	// set default headers
@@ -240,42 +274,59 @@ func addAWSExtensions(op *swg.Operation, key string, method string, endpointUrl
 	requestParams["integration.request.header.accept"] = "method.request.header.accept"
 	requestParams["integration.request.header.content-type"] = "method.request.header.content-type"
 
-	var responses = map[string]map[string]interface{}{}
-
-	for _, element := range mappedErrors {
-		responses[element] = map[string]interface{}{}
-		responses[element]["statusCode"] = element
-		responses[element]["responseParameters"] = map[string]string{
-			"method.response.header.Access-Control-Allow-Origin": "'*'",
-		}
-	}
+	mappings := responseMappings(op, options)
 
 	op.Produces = DEFAULT_JSON_MIME_TYPE
 	log.WithFields(log.Fields{
 		"Endpoint": key,
 	}).Info("Processing endpoint")
 
+	connectionType := strings.ToUpper(options.ConnectionType)
+	if connectionType == "" {
+		connectionType = PublicConnectionType
+	}
+
 	extension := model.AWSAPIGatewayIntegration{
-		ConnectionType:      strings.ToUpper(utils.FetchEnvVar(ConnectionType, PublicConnectionType)),
-		URI:                 fmt.Sprintf("http://%s%s", endpointUrl, key),
-		ConnectionID:        utils.FetchEnvVar(VPCLinkID, ""),
+		ConnectionType:      connectionType,
+		URI:                 endpointUrl + key,
+		ConnectionID:        options.connectionID(),
 		HTTPMethod:          method,
 		IntegrationType:     "http",
 		PassthroughBehavior: "when_no_templates",
 		RequestParameters:   requestParams,
-		Responses:           responses,
+		Responses:           integrationResponses(mappings, corsResponseParameters(options)),
+	}
+
+	backend.apply(&extension, options)
+	for _, override := range overrides {
+		override.apply(&extension, endpointUrl)
+	}
+	if extension.Responses != nil {
+		declareMethodResponses(op, mappings)
+	}
+	// the proxy integrations return the backend response as is, a response is still required by the specification
+	if op.Responses == nil {
+		op.Responses = &swg.Responses{ResponsesProps: swg.ResponsesProps{StatusCodeResponses: map[int]swg.Response{
+			http.StatusOK: *swg.NewResponse().WithDescription(http.StatusText(http.StatusOK)),
+		}}}
 	}
 
 	item := op
+	delete(item.Extensions, IntegrationExtension)
+	delete(item.Extensions, OverrideExtension)
 	item.VendorExtensible.AddExtension("x-amazon-apigateway-integration", extension)
 
-	if securityEnabled {
-		item.SecuredWith(utils.RetrieveEnvVar(AuthName))
+	delete(item.Extensions, AuthorizerExtension)
+	if auth.secured() {
+		secure(item, auth)
 	} else {
+		// the security requirements of the source document reference schemes that are not defined any more
+		item.Security = nil
 		log.WithFields(log.Fields{
 			"Endpoint": key,
 		}).Warn("is marked as having no required authentication")
 	}
+	return extension
 }
 
 // Add parameter to the given path
@@ -287,71 +338,6 @@ func addHeaderParameter(op *swg.Operation, paramName string, in string, required
 	op.Parameters = append(parameters, param)
 }
 
-func addOperationCORSHeaders(op *swg.Operation) {
-	corsHeader := map[string]swg.Header{"Access-Control-Allow-Origin": {
-		SimpleSchema: swg.SimpleSchema{Type: "string"},
-	}}
-
-	for key, response := range op.OperationProps.Responses.ResponsesProps.StatusCodeResponses {
-		response.ResponseProps = swg.ResponseProps{
-			Headers: corsHeader,
-		}
-		op.OperationProps.Responses.ResponsesProps.StatusCodeResponses[key] = response
-	}
-}
-
-func addOptionsCORSSupport(op *swg.Operation, key, method string) {
-	log.WithFields(log.Fields{"URL": key}).Info("Adding CORS Support to endpoint")
-
-	op.OperationProps = swg.OperationProps{
-		Summary:     "CORS Support",
-		Description: "Enable CORS Support by returning correct headers",
-		Consumes:    []string{"text/json", "application/json"},
-		Produces:    []string{"text/json", "application/json"},
-		Responses: &swg.Responses{
-			VendorExtensible: swg.VendorExtensible{},
-			ResponsesProps: swg.ResponsesProps{
-				StatusCodeResponses: map[int]swg.Response{
-					200: {
-						Refable:          swg.Refable{},
-						VendorExtensible: swg.VendorExtensible{},
-						ResponseProps: swg.ResponseProps{
-							Headers: map[string]swg.Header{
-								"Access-Control-Allow-Origin": {
-									SimpleSchema: swg.SimpleSchema{Type: "string"},
-								},
-								"Access-Control-Allow-Methods": {
-									SimpleSchema: swg.SimpleSchema{Type: "string"},
-								},
-								"Access-Control-Allow-Headers": {
-									SimpleSchema: swg.SimpleSchema{Type: "string"},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	extension := model.AWSAPIGatewayIntegration{
-		IntegrationType:     "mock",
-		PassthroughBehavior: "when_no_match",
-		HTTPMethod:          method,
-		RequestTemplates:    map[string]string{"application/json": "{\"statusCode\": 200}"},
-		Responses: map[string]map[string]interface{}{
-			"default": {
-				"statusCode": "200",
-				"responseParameters": map[string]string{
-					"method.response.header.Access-Control-Allow-Methods": "'GET,OPTIONS,PATCH,PUT,POST,DELETE'",
-					"method.response.header.Access-Control-Allow-Headers": "'Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,DNT,Origin,Referer,Sec-Fetch-Mode,User-Agent,Access-Control-Request-Headers,Access-Control-Request-Method,organisation-id'",
-					"method.response.header.Access-Control-Allow-Origin":  "'*'",
-				}},
-		},
-	}
-	op.VendorExtensible.AddExtension("x-amazon-apigateway-integration", extension)
-}
-
 // Remove all the unwanted tags or param not supported by AWS API Gateway REST API
 func applyFilters(swagger *swg.Swagger) {
 	definitions := swagger.Definitions
@@ -414,39 +400,12 @@ func removeTagExample(props *swg.SchemaProps) {
 }
 
 func isPathVisible(path swg.PathItem) bool {
-	values := reflect.ValueOf(path.PathItemProps)
-	num := values.NumField()
-	for i := 0; i < num; i++ {
-		if values.Field(i).IsNil() {
-			continue
-		}
-		value := values.Field(i).Interface().(*swg.Operation)
-		str, ok := value.Extensions.GetString("x-publish")
-
-		if !ok {
-			return true
-		}
-		getBool, err := strconv.ParseBool(str)
-		if err == nil {
-			return getBool
-		}
-	}
-	return true
-}
+	for _, operation := range Operations(path) {
+		str, ok := operation.Extensions.GetString("x-publish")
 
-func isSecurityEnabled(path swg.PathItem) bool {
-	values := reflect.ValueOf(path.PathItemProps)
-	num := values.NumField()
-	for i := 0; i < num; i++ {
-		if values.Field(i).IsNil() {
-			continue
-		}
-		value := values.Field(i).Interface().(*swg.Operation)
-		str, ok := value.Extensions.GetString("x-auth-disabled")
 		if !ok {
 			return true
 		}
-
 		getBool, err := strconv.ParseBool(str)
 		if err == nil {
 			return getBool
@@ -0,0 +1,98 @@
+package swagger
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// PolicyExtension the resource policy of the API
+	PolicyExtension = "x-amazon-apigateway-policy"
+
+	policyVersion  = "2012-10-17"
+	invokeAction   = "execute-api:Invoke"
+	invokeResource = "execute-api:/*"
+)
+
+var (
+	accountIDRegexp   = regexp.MustCompile(`^\d{12}$`)
+	vpcEndpointRegexp = regexp.MustCompile(`^vpce-[0-9a-f]+$`)
+)
+
+// ResourcePolicy the callers allowed to invoke the API, a caller must be one of the principals, any by default, and call
+// from one of the vpc endpoints or source ips when any are listed
+type ResourcePolicy struct {
+	// Principals the account ids or the arns of the roles and users allowed
+	Principals   []string `yaml:"principals"`
+	VPCEndpoints []string `yaml:"vpcEndpoints"`
+	// SourceIPs the addresses or cidr blocks allowed
+	SourceIPs []string `yaml:"sourceIps"`
+}
+
+func (p ResourcePolicy) isEmpty() bool {
+	return len(p.Principals) == 0 && len(p.VPCEndpoints) == 0 && len(p.SourceIPs) == 0
+}
+
+// Validate returns the problems of the policy
+func (p ResourcePolicy) Validate() []string {
+	var problems []string
+	for _, principal := range p.Principals {
+		if !accountIDRegexp.MatchString(principal) && !strings.HasPrefix(principal, iamArnPrefix) {
+			problems = append(problems, fmt.Sprintf("invalid policy principal %q, expected an account id or %s...", principal, iamArnPrefix))
+		}
+	}
+	for _, endpoint := range p.VPCEndpoints {
+		if !vpcEndpointRegexp.MatchString(endpoint) {
+			problems = append(problems, fmt.Sprintf("invalid policy vpc endpoint %q, expected vpce-...", endpoint))
+		}
+	}
+	for _, ip := range p.SourceIPs {
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			problems = append(problems, fmt.Sprintf("invalid policy source ip %q, expected an address or a cidr block", ip))
+		}
+	}
+	return problems
+}
+
+// render returns the policy allowing the principals from any of the vpc endpoints or source ips, one statement per
+// condition as the statements allowing a request are or-ed
+func (p ResourcePolicy) render() model.AWSAPIGatewayPolicy {
+	var principal interface{} = "*"
+	if len(p.Principals) > 0 {
+		principal = map[string][]string{"AWS": p.Principals}
+	}
+	allow := func(condition map[string]map[string]interface{}) model.AWSAPIGatewayPolicyStatement {
+		return model.AWSAPIGatewayPolicyStatement{
+			Effect:    "Allow",
+			Principal: principal,
+			Action:    invokeAction,
+			Resource:  invokeResource,
+			Condition: condition,
+		}
+	}
+
+	policy := model.AWSAPIGatewayPolicy{Version: policyVersion}
+	if len(p.VPCEndpoints) > 0 {
+		policy.Statement = append(policy.Statement, allow(map[string]map[string]interface{}{"StringEquals": {"aws:SourceVpce": p.VPCEndpoints}}))
+	}
+	if len(p.SourceIPs) > 0 {
+		policy.Statement = append(policy.Statement, allow(map[string]map[string]interface{}{"IpAddress": {"aws:SourceIp": p.SourceIPs}}))
+	}
+	if len(policy.Statement) == 0 {
+		policy.Statement = append(policy.Statement, allow(nil))
+	}
+	return policy
+}
+
+// addResourcePolicy adds the resource policy of the options, the one of the vanilla document is kept otherwise
+func addResourcePolicy(doc *swg.Swagger, options Options) {
+	if options.Policy.isEmpty() {
+		return
+	}
+	doc.AddExtension(PolicyExtension, options.Policy.render())
+}
@@ -0,0 +1,89 @@
+package swagger
+
+import (
+	"encoding/json"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldAuthorizeWithIAMAndAddTheResourcePolicy(t *testing.T) {
+
+	t.Logf("Given an iam authorizer for the internal routes and a resource policy")
+	{
+		doc := readAccountSwagger(t)
+		doc.Paths.Paths["/admin/accounts"].Post.AddExtension(AuthorizerExtension, "internal")
+		doc.Paths.Paths["/organisations/{orgId}"].Get.AddExtension(IAMAuthExtension, map[string]interface{}{"type": "aws_iam"})
+
+		options := OptionsFromEnv()
+		options.Authorizers = []Authorizer{{Name: "internal", Type: IAMAuth}}
+		options.Policy = ResourcePolicy{
+			Principals:   []string{"123456789012", "arn:aws:iam::210987654321:role/orders"},
+			VPCEndpoints: []string{"vpce-0a1b2c3d"},
+			SourceIPs:    []string{"10.0.0.0/16"},
+		}
+
+		t.Logf("\tWhen rendering the swagger, the internal routes should be signed with SigV4")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			for _, op := range []*swg.Operation{rendered.Paths.Paths["/admin/accounts"].Post, rendered.Paths.Paths["/organisations/{orgId}"].Get} {
+				auth, _ := op.Extensions[IAMAuthExtension].(map[string]interface{})
+				if auth["type"] == "AWS_IAM" && len(op.Security) == 0 {
+					t.Logf("\t\tThe operation %s should be authorized with iam only %v", op.ID, CheckMark)
+				} else {
+					t.Errorf("\t\tThe operation %s should be authorized with iam only, got %v %v %v", op.ID, auth, op.Security, BallotX)
+				}
+			}
+			if _, ok := rendered.SecurityDefinitions["internal"]; !ok {
+				t.Logf("\t\tThe iam authorizer should have no security definition %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe iam authorizer should have no security definition %v", BallotX)
+			}
+
+			var policy struct {
+				Statement []struct {
+					Effect    string
+					Principal map[string][]string
+					Condition map[string]map[string][]string
+				}
+			}
+			raw, _ := json.Marshal(rendered.Extensions[PolicyExtension])
+			json.Unmarshal(raw, &policy)
+			if len(policy.Statement) == 2 {
+				t.Logf("\t\tThe policy should allow the vpc endpoint or the source ips %v", CheckMark)
+			} else {
+				t.Fatalf("\t\tThe policy should allow the vpc endpoint or the source ips, got %s %v", raw, BallotX)
+			}
+			if vpce := policy.Statement[0].Condition["StringEquals"]["aws:SourceVpce"]; len(vpce) == 1 && vpce[0] == "vpce-0a1b2c3d" {
+				t.Logf("\t\tThe first statement should allow the vpc endpoint %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe first statement should allow the vpc endpoint, got %s %v", raw, BallotX)
+			}
+			if principals := policy.Statement[1].Principal["AWS"]; len(principals) == 2 {
+				t.Logf("\t\tThe statements should allow the principals only %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe statements should allow the principals only, got %s %v", raw, BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given a resource policy with invalid entries")
+	{
+		policy := ResourcePolicy{Principals: []string{"orders"}, VPCEndpoints: []string{"vpc-123"}, SourceIPs: []string{"10.0.0.0/33"}}
+
+		t.Logf("\tWhen validating the policy, every entry should be reported")
+		{
+			if problems := policy.Validate(); len(problems) == 3 {
+				t.Logf("\t\tThe invalid entries should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe invalid entries should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
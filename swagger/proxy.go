@@ -0,0 +1,93 @@
+package swagger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ProxyEnabled = "PROXY_ENABLED"
+	// ProxyAuthorizer the name of the authorizer guarding the greedy route, the default authorizer when not set
+	ProxyAuthorizer = "PROXY_AUTHORIZER"
+	// ProxyAPIKeyRequired the greedy route requires the callers to send an api key of a usage plan
+	ProxyAPIKeyRequired = "PROXY_API_KEY_REQUIRED"
+	// ProxyPath the greedy path matching all the routes not defined in the document
+	ProxyPath = "/{proxy+}"
+)
+
+// addProxyRoute adds the greedy `ANY /{proxy+}` route passing all the requests through to the backend and returns its
+// integration and whether it is secured
+func addProxyRoute(doc *swg.Swagger, endpointUrl string, options Options) (model.AWSAPIGatewayIntegration, bool) {
+	log.WithFields(log.Fields{"Endpoint": ProxyPath}).Info("Adding greedy proxy route")
+
+	op := swg.NewOperation("proxy")
+	op.Produces = DEFAULT_JSON_MIME_TYPE
+	op.Responses = &swg.Responses{ResponsesProps: swg.ResponsesProps{
+		StatusCodeResponses: map[int]swg.Response{200: {ResponseProps: swg.ResponseProps{Description: "Passed through from the backend"}}},
+	}}
+	op.AddParam(swg.PathParam("proxy").Typed("string", ""))
+
+	connectionType := strings.ToUpper(options.ConnectionType)
+	if connectionType == "" {
+		connectionType = PublicConnectionType
+	}
+	integration := model.AWSAPIGatewayIntegration{
+		ConnectionType:      connectionType,
+		URI:                 endpointUrl + "/{proxy}",
+		ConnectionID:        options.connectionID(),
+		HTTPMethod:          AnyMethod,
+		IntegrationType:     "http_proxy",
+		PassthroughBehavior: "when_no_match",
+		RequestParameters:   map[string]string{"integration.request.path.proxy": "method.request.path.proxy"},
+	}
+	op.AddExtension("x-amazon-apigateway-integration", integration)
+	authorizer, secured := options.proxyAuthorizer()
+	if secured {
+		secure(op, authorizer.authorization(nil))
+	}
+	if options.ProxyAPIKeyRequired {
+		requireAPIKey(op)
+		addAPIKeyDefinition(doc)
+	}
+
+	item := swg.PathItem{}
+	item.AddExtension(AnyMethodExtension, op)
+	if options.CorsEnabled {
+		item.Options = swg.NewOperation("add_cors")
+		addOptionsCORSSupport(item.Options, ProxyPath, options.Cors.allowMethods(item), options)
+	}
+	doc.Paths.Paths[ProxyPath] = item
+	return integration, secured
+}
+
+// proxyAuthorizer returns the authorizer guarding the greedy route: the one named by ProxyAuthorizer, else the default
+// authorizer when set
+func (o Options) proxyAuthorizer() (Authorizer, bool) {
+	if o.ProxyAuthorizer == "" {
+		return o.authorizer(), o.hasAuthorizer()
+	}
+	for _, authorizer := range o.authorizers() {
+		if authorizer.Name == o.ProxyAuthorizer {
+			return authorizer, true
+		}
+	}
+	return Authorizer{}, false
+}
+
+// validateProxy checks the greedy route is guarded by a known authorizer
+func (o Options) validateProxy() []string {
+	if !o.ProxyEnabled {
+		return nil
+	}
+	if _, ok := o.proxyAuthorizer(); ok {
+		return nil
+	}
+	if o.ProxyAuthorizer != "" {
+		return []string{fmt.Sprintf("the proxy authorizer (--proxy-authorizer or %s) %q is not defined", ProxyAuthorizer, o.ProxyAuthorizer)}
+	}
+	return []string{fmt.Sprintf("the proxy authorizer (--proxy-authorizer or %s) is required when the greedy proxy has no default authorizer", ProxyAuthorizer)}
+}
@@ -0,0 +1,91 @@
+package swagger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+)
+
+// RenderReport summarises what a rendered swagger document will publish to API Gateway
+type RenderReport struct {
+	Published    []string            `json:"published"`
+	Skipped      []string            `json:"skipped"`
+	Secured      []string            `json:"secured"`
+	Integrations []IntegrationReport `json:"integrations"`
+}
+
+// IntegrationReport the integration generated for a given operation
+type IntegrationReport struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+	Type   string `json:"type"`
+	URI    string `json:"uri"`
+}
+
+func (report *RenderReport) addOperation(key, method string, integration model.AWSAPIGatewayIntegration, secured bool) {
+	if secured {
+		report.Secured = append(report.Secured, fmt.Sprintf("%s %s", method, key))
+	}
+	report.Integrations = append(report.Integrations, IntegrationReport{
+		Path:   key,
+		Method: method,
+		Type:   integration.IntegrationType,
+		URI:    integration.URI,
+	})
+}
+
+// merge adds the report of a service published under the given base path
+func (report *RenderReport) merge(other RenderReport, basePath string) {
+	for _, path := range other.Published {
+		report.Published = append(report.Published, joinPath(basePath, path))
+	}
+	for _, path := range other.Skipped {
+		report.Skipped = append(report.Skipped, joinPath(basePath, path))
+	}
+	for _, operation := range other.Secured {
+		parts := strings.SplitN(operation, " ", 2)
+		report.Secured = append(report.Secured, fmt.Sprintf("%s %s", parts[0], joinPath(basePath, parts[1])))
+	}
+	for _, integration := range other.Integrations {
+		integration.Path = joinPath(basePath, integration.Path)
+		report.Integrations = append(report.Integrations, integration)
+	}
+}
+
+// paths are rendered from a map, sorting them keeps the report stable between runs
+func (report *RenderReport) sort() {
+	sort.Strings(report.Published)
+	sort.Strings(report.Skipped)
+	sort.Strings(report.Secured)
+	sort.Slice(report.Integrations, func(i, j int) bool {
+		if report.Integrations[i].Path == report.Integrations[j].Path {
+			return report.Integrations[i].Method < report.Integrations[j].Method
+		}
+		return report.Integrations[i].Path < report.Integrations[j].Path
+	})
+}
+
+// String renders the report in a human readable form
+func (report RenderReport) String() string {
+	var builder strings.Builder
+	writeSection(&builder, "Published paths", report.Published)
+	writeSection(&builder, "Skipped paths", report.Skipped)
+	writeSection(&builder, "Secured operations", report.Secured)
+
+	var integrations []string
+	for _, integration := range report.Integrations {
+		integrations = append(integrations, fmt.Sprintf("%-7s %s -> [%s] %s",
+			integration.Method, integration.Path, integration.Type, integration.URI))
+	}
+	writeSection(&builder, "Integrations", integrations)
+	return builder.String()
+}
+
+func writeSection(builder *strings.Builder, title string, lines []string) {
+	fmt.Fprintf(builder, "%s (%d):\n", title, len(lines))
+	for _, line := range lines {
+		fmt.Fprintf(builder, "  %s\n", line)
+	}
+}
@@ -0,0 +1,59 @@
+package swagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestSwaggerClient_RenderSwaggerWithReportShouldListPublishedAndSkippedPaths(t *testing.T) {
+
+	t.Logf("Given we read swagger from the deployed account service")
+	{
+		t.Logf("\tWhen calling RenderSwaggerWithReport method, it should report the published and skipped paths")
+		{
+			var data swg.Swagger
+			swagger, _ := ioutil.ReadFile("../data/swagger.json")
+
+			decoder := json.NewDecoder(bytes.NewReader(swagger))
+			decoder.Decode(&data)
+
+			client := NewSwaggerClient("account-service")
+			_, report, err := client.RenderSwaggerWithReport(data)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger document %v %v", err, BallotX)
+			}
+
+			if len(report.Skipped) == 1 && report.Skipped[0] == "/admin/accounts/{accountId}" {
+				t.Logf("\t\tPaths with x-publish set to false should be reported as skipped %v", CheckMark)
+			} else {
+				t.Errorf("\t\tPaths with x-publish set to false should be reported as skipped, got %v %v", report.Skipped, BallotX)
+			}
+
+			if len(report.Published) == 6 {
+				t.Logf("\t\tAll the other paths should be reported as published %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAll the other paths should be reported as published, got %v %v", report.Published, BallotX)
+			}
+
+			for _, integration := range report.Integrations {
+				if integration.Path == "/admin/accounts/{accountId}" {
+					t.Errorf("\t\tSkipped paths should not have an integration %v", BallotX)
+				}
+				if !strings.HasSuffix(integration.URI, integration.Path) {
+					t.Errorf("\t\tIntegration uri %s should target the path %s %v", integration.URI, integration.Path, BallotX)
+				}
+			}
+
+			if strings.Contains(report.String(), "Skipped paths (1):\n  /admin/accounts/{accountId}") {
+				t.Logf("\t\tThe summary should list the skipped paths %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe summary should list the skipped paths %v", BallotX)
+			}
+		}
+	}
+}
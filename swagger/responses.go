@@ -0,0 +1,210 @@
+package swagger
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	// ResponseHeaders the headers passed through from the backend on every response, exp: Location,ETag
+	ResponseHeaders = "RESPONSE_HEADERS"
+
+	allowOriginHeader = "Access-Control-Allow-Origin"
+
+	// the catch-all selection patterns of the backend responses without an integration response of their status code
+	clientErrorPattern = `4\d{2}`
+	serverErrorPattern = `5\d{2}`
+	defaultPattern     = "default"
+)
+
+var statusCodeRegexp = regexp.MustCompile(`^[1-5]\d{2}$`)
+
+// ResponseMapping an integration response, the backend responses matching the selection pattern are returned with the
+// status code, the passed through headers and the transformed body
+type ResponseMapping struct {
+	StatusCode string `json:"statusCode" yaml:"statusCode"`
+	// SelectionPattern the regular expression matched against the status code of the backend, defaults to the status code
+	SelectionPattern string `json:"selectionPattern,omitempty" yaml:"selectionPattern"`
+	// Headers passed through from the backend response
+	Headers []string `json:"headers,omitempty" yaml:"headers"`
+	// Templates the response templates per content type
+	Templates map[string]string `json:"templates,omitempty" yaml:"templates"`
+}
+
+// DefaultResponseMappings the integration responses of every operation when none are configured, the other client and
+// server errors are returned as a 400 and 500, any other response as a 200
+func DefaultResponseMappings() []ResponseMapping {
+	var mappings []ResponseMapping
+	for _, statusCode := range mappedErrors {
+		mappings = append(mappings, ResponseMapping{StatusCode: statusCode})
+	}
+	return append(mappings,
+		ResponseMapping{StatusCode: "400", SelectionPattern: clientErrorPattern},
+		ResponseMapping{StatusCode: "500", SelectionPattern: serverErrorPattern},
+		ResponseMapping{StatusCode: "200", SelectionPattern: defaultPattern},
+	)
+}
+
+// Validate returns the problems of the mapping
+func (m ResponseMapping) Validate() []string {
+	var problems []string
+	if !statusCodeRegexp.MatchString(m.StatusCode) {
+		problems = append(problems, fmt.Sprintf("invalid response status code %q", m.StatusCode))
+	}
+	if _, err := regexp.Compile(m.SelectionPattern); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid selection pattern %q of the %s response: %s", m.SelectionPattern, m.StatusCode, err))
+	}
+	return problems
+}
+
+func (m ResponseMapping) selectionPattern() string {
+	if m.SelectionPattern == "" {
+		return m.StatusCode
+	}
+	return m.SelectionPattern
+}
+
+// responseMappings returns the mappings of the operation: the configured ones, then one per response the operation
+// declares, passing its headers through
+func responseMappings(op *swg.Operation, options Options) []ResponseMapping {
+	mappings := options.ResponseMappings
+	if mappings == nil {
+		mappings = DefaultResponseMappings()
+	}
+
+	byStatus := map[string]int{}
+	var result []ResponseMapping
+	for _, mapping := range mappings {
+		mapping.Headers = append(append([]string{}, options.ResponseHeaders...), mapping.Headers...)
+		if mapping.isExact() {
+			byStatus[mapping.StatusCode] = len(result)
+		}
+		result = append(result, mapping)
+	}
+
+	for _, code := range declaredStatusCodes(op) {
+		statusCode := strconv.Itoa(code)
+		var headers []string
+		for name := range op.Responses.StatusCodeResponses[code].Headers {
+			if !strings.EqualFold(name, allowOriginHeader) {
+				headers = append(headers, name)
+			}
+		}
+		sort.Strings(headers)
+		if i, ok := byStatus[statusCode]; ok {
+			result[i].Headers = append(result[i].Headers, headers...)
+			continue
+		}
+		byStatus[statusCode] = len(result)
+		result = append(result, ResponseMapping{StatusCode: statusCode, Headers: append(append([]string{}, options.ResponseHeaders...), headers...)})
+	}
+
+	// the catch-alls must not match the status codes mapped exactly, API Gateway does not say which pattern wins
+	for i, mapping := range result {
+		switch mapping.SelectionPattern {
+		case clientErrorPattern, serverErrorPattern:
+			result[i].SelectionPattern = excludingPattern(mapping.SelectionPattern, byStatus)
+		}
+	}
+	return result
+}
+
+func (m ResponseMapping) isExact() bool {
+	return m.selectionPattern() == m.StatusCode
+}
+
+// excludingPattern returns the catch-all pattern of a status class not matching the given status codes of the class,
+// exp: 4(?!00|04)\d{2}
+func excludingPattern(pattern string, statusCodes map[string]int) string {
+	class := pattern[:1]
+	var excluded []string
+	for statusCode := range statusCodes {
+		if strings.HasPrefix(statusCode, class) {
+			excluded = append(excluded, statusCode[1:])
+		}
+	}
+	if len(excluded) == 0 {
+		return pattern
+	}
+	sort.Strings(excluded)
+	return fmt.Sprintf("%s(?!%s)%s", class, strings.Join(excluded, "|"), pattern[1:])
+}
+
+// integrationResponses returns the responses of the integration keyed by selection pattern, with the cors headers
+func integrationResponses(mappings []ResponseMapping, corsParameters map[string]string) map[string]map[string]interface{} {
+	responses := map[string]map[string]interface{}{}
+	for _, mapping := range mappings {
+		parameters := map[string]string{}
+		for name, value := range corsParameters {
+			parameters[fmt.Sprintf("method.response.header.%s", name)] = value
+		}
+		for _, header := range mapping.Headers {
+			parameters[fmt.Sprintf("method.response.header.%s", header)] = fmt.Sprintf("integration.response.header.%s", header)
+		}
+		response := map[string]interface{}{
+			"statusCode":         mapping.StatusCode,
+			"responseParameters": parameters,
+		}
+		if len(mapping.Templates) > 0 {
+			response["responseTemplates"] = mapping.Templates
+		}
+		responses[mapping.selectionPattern()] = response
+	}
+	return responses
+}
+
+// declareMethodResponses adds the method responses the integration responses are mapped to along with their passed
+// through headers, API Gateway rejects the mappings to undeclared responses and headers
+func declareMethodResponses(op *swg.Operation, mappings []ResponseMapping) {
+	if op.Responses == nil {
+		op.Responses = &swg.Responses{}
+	}
+	if op.Responses.StatusCodeResponses == nil {
+		op.Responses.StatusCodeResponses = map[int]swg.Response{}
+	}
+	for _, mapping := range mappings {
+		code, _ := strconv.Atoi(mapping.StatusCode)
+		response, ok := op.Responses.StatusCodeResponses[code]
+		if !ok {
+			response.Description = http.StatusText(code)
+		}
+		for _, header := range mapping.Headers {
+			if _, ok := response.Headers[header]; !ok {
+				response.AddHeader(header, swg.ResponseHeader().Typed("string", ""))
+			}
+		}
+		op.Responses.StatusCodeResponses[code] = response
+	}
+}
+
+func declaredStatusCodes(op *swg.Operation) []int {
+	if op.Responses == nil {
+		return nil
+	}
+	var codes []int
+	for code := range op.Responses.StatusCodeResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// validateResponseMappings checks the configured integration responses
+func validateResponseMappings(mappings []ResponseMapping) []string {
+	var problems []string
+	patterns := map[string]bool{}
+	for _, mapping := range mappings {
+		problems = append(problems, mapping.Validate()...)
+		if patterns[mapping.selectionPattern()] {
+			problems = append(problems, fmt.Sprintf("the selection pattern %q is used by more than one response", mapping.selectionPattern()))
+		}
+		patterns[mapping.selectionPattern()] = true
+	}
+	return problems
+}
@@ -0,0 +1,131 @@
+package swagger
+
+import (
+	"encoding/json"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldMapTheDeclaredAndConfiguredResponses(t *testing.T) {
+
+	t.Logf("Given an operation declaring a 429 response with a Retry-After header and a configured 503 response")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		tooMany := swg.NewResponse().WithDescription("Too many requests")
+		tooMany.AddHeader("Retry-After", swg.ResponseHeader().Typed("integer", ""))
+		item.Get.RespondsWith(429, tooMany)
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+
+		options := OptionsFromEnv()
+		options.ResponseHeaders = []string{"ETag"}
+		options.ResponseMappings = append(DefaultResponseMappings(), ResponseMapping{
+			StatusCode:       "503",
+			SelectionPattern: "5(02|03|04)",
+			Templates:        map[string]string{"application/json": `{"message": "unavailable"}`},
+		})
+
+		t.Logf("\tWhen rendering the swagger, an integration response should be generated for each of them")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+			get := rendered.Paths.Paths["/accounts/{accountId}"].Get
+			responses := integrationOf(get).Responses
+
+			parameters, _ := responses["429"]["responseParameters"].(map[string]interface{})
+			if parameters["method.response.header.Retry-After"] == "integration.response.header.Retry-After" &&
+				parameters["method.response.header.ETag"] == "integration.response.header.ETag" {
+				t.Logf("\t\tThe declared response should pass its headers through %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe declared response should pass its headers through, got %v %v", responses["429"], BallotX)
+			}
+
+			unavailable := responses["5(02|03|04)"]
+			if unavailable["statusCode"] == "503" && unavailable["responseTemplates"] != nil {
+				t.Logf("\t\tThe configured response should be selected with its pattern %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe configured response should be selected with its pattern, got %v %v", responses, BallotX)
+			}
+
+			response, ok := get.Responses.StatusCodeResponses[503]
+			if _, etag := response.Headers["ETag"]; ok && etag {
+				t.Logf("\t\tThe method response of the configured response should be declared %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe method response of the configured response should be declared %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldMapTheUndeclaredStatusCodesWithTheCatchAlls(t *testing.T) {
+
+	t.Logf("Given the default response mappings and cors enabled")
+	{
+		options := OptionsFromEnv()
+		options.CorsEnabled = true
+
+		t.Logf("\tWhen rendering the swagger, the undeclared status codes should be caught")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(readAccountSwagger(t))
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+			get := rendered.Paths.Paths["/accounts/{accountId}"].Get
+			responses := integrationOf(get).Responses
+
+			expected := map[string]string{
+				`4(?!00|01|03|04|09|24)\d{2}`: "400",
+				`5(?!00)\d{2}`:                "500",
+				"default":                     "200",
+			}
+			for pattern, statusCode := range expected {
+				if responses[pattern]["statusCode"] == statusCode {
+					t.Logf("\t\tThe %s pattern should be mapped to %s %v", pattern, statusCode, CheckMark)
+				} else {
+					t.Errorf("\t\tThe %s pattern should be mapped to %s, got %v %v", pattern, statusCode, responses, BallotX)
+				}
+			}
+			if _, ok := responses["400"]; ok {
+				t.Logf("\t\tThe exact 400 mapping should be kept %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe exact 400 mapping should be kept %v", BallotX)
+			}
+
+			response := get.Responses.StatusCodeResponses[424]
+			if _, cors := response.Headers["Access-Control-Allow-Origin"]; cors && response.Description != "" {
+				t.Logf("\t\tThe declared method response should keep its description along with the cors headers %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe declared method response should keep its description along with the cors headers, got %+v %v", response, BallotX)
+			}
+		}
+	}
+}
+
+func TestOptionsValidate_ShouldReportInvalidResponseMappings(t *testing.T) {
+
+	t.Logf("Given response mappings with an invalid status code, pattern and a duplicate pattern")
+	{
+		options := Options{APIGatewayName: "api-gw-dev", AuthType: IAMAuth, ResponseMappings: []ResponseMapping{
+			{StatusCode: "2xx"},
+			{StatusCode: "500", SelectionPattern: "5("},
+			{StatusCode: "404"},
+			{StatusCode: "410", SelectionPattern: "404"},
+		}}
+
+		t.Logf("\tWhen validating the options, every problem should be reported")
+		{
+			if problems := options.Validate(); len(problems) == 3 {
+				t.Logf("\t\tThe mapping problems should be reported %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe mapping problems should be reported, got %v %v", problems, BallotX)
+			}
+		}
+	}
+}
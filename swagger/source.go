@@ -0,0 +1,58 @@
+package swagger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	StdinSource = "-"
+	FileScheme  = "file://"
+)
+
+// readSource reads the swagger document from the given source: an http(s) url, a `file://` url, a file path or `-` for stdin
+func (client SwaggerParser) readSource() ([]byte, error) {
+	source := client.swaggerUrl
+	switch {
+	case source == StdinSource:
+		log.Info("Reading vanilla swagger from stdin")
+		return ioutil.ReadAll(os.Stdin)
+	case strings.HasPrefix(source, FileScheme):
+		// the path follows the scheme as is, relative paths such as file://target/openapi.yaml included
+		return readFile(strings.TrimPrefix(source, FileScheme))
+	case isRemote(source):
+		return client.fetchRemote()
+	default:
+		return readFile(source)
+	}
+}
+
+func readFile(path string) ([]byte, error) {
+	log.WithFields(log.Fields{"file": path}).Info("Reading vanilla swagger from file")
+	return ioutil.ReadFile(path)
+}
+
+func isRemote(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// toJSON converts yaml documents to json, json documents are returned as is
+func toJSON(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] == '{' {
+		return data, nil
+	}
+
+	log.Info("Converting yaml swagger document to json")
+	doc, err := swag.BytesToYAMLDoc(data)
+	if err != nil {
+		return nil, err
+	}
+	return swag.YAMLToJSON(doc)
+}
@@ -0,0 +1,76 @@
+package swagger
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFetchSwagger_ShouldReadLocalFilesAndStdin(t *testing.T) {
+
+	t.Logf("Given the swagger document is available on the local filesystem")
+	{
+		expected, err := NewSwaggerClient("../data/swagger.json").FetchSwagger()
+		if err != nil {
+			t.Fatalf("\t\tFailed to read the swagger document from a file path %v %v", err, BallotX)
+		}
+		t.Logf("\tWhen fetching the swagger from a plain file path, it should be read from the file %v", CheckMark)
+
+		t.Logf("\tWhen fetching the swagger from a file url, it should be read from the file")
+		{
+			path, _ := filepath.Abs("../data/swagger.json")
+			doc, err := NewSwaggerClient(FileScheme + path).FetchSwagger()
+			if err == nil && reflect.DeepEqual(doc, expected) {
+				t.Logf("\t\tThe document should match the one read from the file path %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe document should match the one read from the file path %v %v", err, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the swagger from a relative file url, it should be read from the relative path")
+		{
+			doc, err := NewSwaggerClient(FileScheme + "../data/swagger.json").FetchSwagger()
+			if err == nil && reflect.DeepEqual(doc, expected) {
+				t.Logf("\t\tThe document should match the one read from the file path %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe document should match the one read from the file path %v %v", err, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the swagger from stdin, it should be read from stdin")
+		{
+			file, _ := os.Open("../data/swagger.json")
+			defer file.Close()
+			stdin := os.Stdin
+			os.Stdin = file
+			defer func() { os.Stdin = stdin }()
+
+			doc, err := NewSwaggerClient(StdinSource).FetchSwagger()
+			if err == nil && reflect.DeepEqual(doc, expected) {
+				t.Logf("\t\tThe document should match the one read from the file path %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe document should match the one read from the file path %v %v", err, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching a yaml swagger, it should be converted to json")
+		{
+			doc, err := NewSwaggerClient("../data/swagger.yaml").FetchSwagger()
+			if err == nil && reflect.DeepEqual(doc, expected) {
+				t.Logf("\t\tThe document should match the json one %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe document should match the json one %v %v", err, BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching a missing file, it should return an error")
+		{
+			if _, err := NewSwaggerClient("../data/missing.json").FetchSwagger(); err != nil {
+				t.Logf("\t\tAn error should be returned %v", CheckMark)
+			} else {
+				t.Errorf("\t\tAn error should be returned %v", BallotX)
+			}
+		}
+	}
+}
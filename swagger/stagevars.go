@@ -0,0 +1,56 @@
+package swagger
+
+import (
+	"fmt"
+	"strings"
+
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	StageVariablesEnabled = "STAGE_VARIABLES_ENABLED"
+
+	// EndpointURLVariable and VPCLinkIDVariable the stage variables referenced by the integrations, prefixed with the
+	// camel cased service name when several services are merged, exp: accountServiceEndpointUrl
+	EndpointURLVariable = "endpointUrl"
+	VPCLinkIDVariable   = "vpcLinkId"
+)
+
+// stageVariable returns the name of the stage variable, prefixed with the service name when set
+func (o Options) stageVariable(name string) string {
+	if o.StageVariablePrefix == "" {
+		return name
+	}
+	prefixed := namespace(o.StageVariablePrefix, strings.ToUpper(name[:1])+name[1:])
+	return strings.ToLower(prefixed[:1]) + prefixed[1:]
+}
+
+// stageVariableReference returns the reference to the stage variable used in the integrations
+func (o Options) stageVariableReference(name string) string {
+	return fmt.Sprintf("${stageVariables.%s}", o.stageVariable(name))
+}
+
+// connectionID returns the vpc link id of the integrations, a reference to its stage variable when they are enabled
+func (o Options) connectionID() string {
+	if o.StageVariablesEnabled && strings.ToUpper(o.ConnectionType) == VPCLinkConnectionType {
+		return o.stageVariableReference(VPCLinkIDVariable)
+	}
+	return o.VPCLinkID
+}
+
+// StageVariables - Function
+// Returns the stage variables referenced by the integrations along with their values, they are set on the stage when
+// deploying so the same document can be promoted from one stage to the next
+func (o Options) StageVariables() map[string]string {
+	if !o.StageVariablesEnabled {
+		return nil
+	}
+	literal := o
+	literal.StageVariablesEnabled = false
+	url := backendURL(swg.Swagger{}, literal)
+	variables := map[string]string{o.stageVariable(EndpointURLVariable): url[strings.Index(url, schemeSeparator)+len(schemeSeparator):]}
+	if strings.ToUpper(o.ConnectionType) == VPCLinkConnectionType {
+		variables[o.stageVariable(VPCLinkIDVariable)] = o.VPCLinkID
+	}
+	return variables
+}
@@ -0,0 +1,70 @@
+package swagger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akhettar/apigw-pub/model"
+	swg "github.com/go-openapi/spec"
+)
+
+const (
+	RequestValidator = "REQUEST_VALIDATOR"
+
+	// RequestValidatorsExtension the validators of the document, RequestValidatorExtension the one of an operation, set
+	// on the operation or its path of the vanilla swagger to override the default one
+	RequestValidatorsExtension = "x-amazon-apigateway-request-validators"
+	RequestValidatorExtension  = "x-amazon-apigateway-request-validator"
+
+	NoValidator     = "none"
+	BodyValidator   = "body"
+	ParamsValidator = "params"
+	FullValidator   = "full"
+)
+
+// requestValidators the validators an operation can be assigned
+var requestValidators = map[string]model.AWSAPIGatewayRequestValidator{
+	BodyValidator:   {ValidateRequestBody: true},
+	ParamsValidator: {ValidateRequestParameters: true},
+	FullValidator:   {ValidateRequestBody: true, ValidateRequestParameters: true},
+}
+
+// resolveValidator returns the validator of the operation: its extension, then the one of its path, then the default one
+func resolveValidator(path swg.PathItem, operation Operation, key string, options Options) (string, error) {
+	validator := options.RequestValidator
+	for _, extensions := range []swg.Extensions{operation.Extensions, path.Extensions} {
+		if value, ok := extensions.GetString(RequestValidatorExtension); ok {
+			validator = value
+			break
+		}
+	}
+	validator = strings.ToLower(validator)
+	if !isValidator(validator) {
+		return "", fmt.Errorf("unsupported request validator %q on %s %s, expected one of %s", validator, operation.Method, key, validatorNames())
+	}
+	return validator, nil
+}
+
+// setValidator assigns the validator to the operation, the vanilla extension is replaced
+func setValidator(op *swg.Operation, validator string) {
+	delete(op.Extensions, RequestValidatorExtension)
+	if validator != "" && validator != NoValidator {
+		op.AddExtension(RequestValidatorExtension, validator)
+	}
+}
+
+// addRequestValidators declares the validators once at least one operation is assigned one
+func addRequestValidators(doc *swg.Swagger, used bool) {
+	if used {
+		doc.AddExtension(RequestValidatorsExtension, requestValidators)
+	}
+}
+
+func isValidator(validator string) bool {
+	_, ok := requestValidators[validator]
+	return ok || validator == "" || validator == NoValidator
+}
+
+func validatorNames() string {
+	return strings.Join([]string{NoValidator, BodyValidator, ParamsValidator, FullValidator}, ", ")
+}
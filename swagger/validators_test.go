@@ -0,0 +1,77 @@
+package swagger
+
+import (
+	"encoding/json"
+	"testing"
+
+	swg "github.com/go-openapi/spec"
+)
+
+func TestRenderSwagger_ShouldAssignTheRequestValidators(t *testing.T) {
+
+	t.Logf("Given the default validator is full and an operation and a path override it")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		item.Get.AddExtension(RequestValidatorExtension, "params")
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+		item = doc.Paths.Paths["/admin/accounts"]
+		item.AddExtension(RequestValidatorExtension, "none")
+		doc.Paths.Paths["/admin/accounts"] = item
+
+		options := OptionsFromEnv()
+		options.RequestValidator = FullValidator
+
+		t.Logf("\tWhen rendering the swagger, the validators should be declared and assigned per operation")
+		{
+			data, _, err := NewSwaggerClientWithOptions("account-service", options).RenderSwaggerWithReport(doc)
+			if err != nil {
+				t.Fatalf("\t\tFailed to render the swagger %v %v", err, BallotX)
+			}
+			var rendered swg.Swagger
+			json.Unmarshal(data, &rendered)
+
+			if validators, ok := rendered.Extensions[RequestValidatorsExtension].(map[string]interface{}); ok && len(validators) == 3 {
+				t.Logf("\t\tThe body, params and full validators should be declared %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe body, params and full validators should be declared, got %v %v", rendered.Extensions, BallotX)
+			}
+
+			path := rendered.Paths.Paths["/accounts/{accountId}"]
+			get, _ := path.Get.Extensions.GetString(RequestValidatorExtension)
+			put, _ := path.Put.Extensions.GetString(RequestValidatorExtension)
+			if get == ParamsValidator && put == FullValidator {
+				t.Logf("\t\tThe operation extension should override the default validator %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe operation extension should override the default validator, got %s and %s %v", get, put, BallotX)
+			}
+
+			admin := rendered.Paths.Paths["/admin/accounts"]
+			if _, ok := admin.Post.Extensions[RequestValidatorExtension]; !ok {
+				t.Logf("\t\tThe operations of a path with the none validator should not be validated %v", CheckMark)
+			} else {
+				t.Errorf("\t\tThe operations of a path with the none validator should not be validated %v", BallotX)
+			}
+		}
+	}
+}
+
+func TestRenderSwagger_ShouldRejectAnUnknownRequestValidator(t *testing.T) {
+
+	t.Logf("Given an operation with an unknown validator")
+	{
+		doc := readAccountSwagger(t)
+		item := doc.Paths.Paths["/accounts/{accountId}"]
+		item.Get.AddExtension(RequestValidatorExtension, "strict")
+		doc.Paths.Paths["/accounts/{accountId}"] = item
+
+		t.Logf("\tWhen rendering the swagger, an error should be returned")
+		{
+			if _, _, err := NewSwaggerClient("account-service").RenderSwaggerWithReport(doc); err != nil {
+				t.Logf("\t\tThe unknown validator should be rejected: %v %v", err, CheckMark)
+			} else {
+				t.Errorf("\t\tThe unknown validator should be rejected %v", BallotX)
+			}
+		}
+	}
+}